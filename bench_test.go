@@ -0,0 +1,129 @@
+package mesh_test
+
+import (
+	"bytes"
+	"github.com/MojaveMF/mesh"
+	"io"
+	"os"
+	"testing"
+)
+
+func benchmarkFile(b *testing.B, path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+func benchmarkDecode(b *testing.B, path string) {
+	data := benchmarkFile(b, path)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := mesh.DecodeMesh(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeV2(b *testing.B) { benchmarkDecode(b, "./testdata/output.v2") }
+func BenchmarkDecodeV3(b *testing.B) { benchmarkDecode(b, "./testdata/output.v3") }
+func BenchmarkDecodeV4(b *testing.B) { benchmarkDecode(b, "./testdata/output.v4") }
+
+/* How the streams used to decode, one binary.Read per element */
+func BenchmarkDecodePerElementV3(b *testing.B) {
+	data := benchmarkFile(b, "./testdata/output.v3")
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stream := mesh.MeshStream3{bytes.NewReader(data[13:])}
+		header, err := stream.ReadHeader()
+		if err != nil {
+			b.Fatal(err)
+		}
+		verts := make([]mesh.VertexModern, header.NumVerts)
+		for v := range verts {
+			if err := stream.ReadValue(&verts[v]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		faces := make([]mesh.Face, header.NumFaces)
+		for f := range faces {
+			if err := stream.ReadValue(&faces[f]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func benchmarkWrite(b *testing.B, decoded interface{ Write(io.Writer) error }) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := decoded.Write(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkMesh4(b *testing.B) *mesh.Mesh4 {
+	decoded, err := mesh.DecodeMesh(bytes.NewReader(benchmarkFile(b, "./testdata/output.v4")))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return decoded.ExportV4()
+}
+
+func BenchmarkWriteV1(b *testing.B) {
+	stream := mesh.MeshStream1{bytes.NewReader(benchmarkFile(b, "./testdata/output.v1")[13:])}
+	mesh1, err := stream.LoadMesh()
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkWrite(b, mesh1)
+}
+func BenchmarkWriteV2(b *testing.B) { benchmarkWrite(b, benchmarkMesh4(b).ExportV2()) }
+func BenchmarkWriteV3(b *testing.B) { benchmarkWrite(b, benchmarkMesh4(b).ExportV3()) }
+func BenchmarkWriteV4(b *testing.B) { benchmarkWrite(b, benchmarkMesh4(b)) }
+
+/* v3 to v4 skips the Model, the second one shows what the copy would cost */
+func benchmarkV3(b *testing.B) *mesh.Mesh3 {
+	decoded, err := mesh.DecodeMesh(bytes.NewReader(benchmarkFile(b, "./testdata/output.v3")))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return decoded.ExportV3()
+}
+
+func BenchmarkEncodeV3ToV4(b *testing.B) {
+	mesh3 := benchmarkV3(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := mesh.EncodeMeshVersion(mesh3, mesh.MeshVersion4).Write(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkModelV3ToV4(b *testing.B) {
+	mesh3 := benchmarkV3(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := mesh3.ToModel().ExportV4().Write(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeBytesAlignedV4(b *testing.B) {
+	data := benchmarkFile(b, "./testdata/output.v4")
+	aligned := mesh.AlignedBuffer(len(data))
+	copy(aligned, data)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := mesh.DecodeBytes(aligned); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
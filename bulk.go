@@ -0,0 +1,68 @@
+package mesh
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"unsafe"
+)
+
+var ErrCountMismatch = errors.New("mesh header counts more items than the mesh holds")
+
+/*
+None of the file structs have padding, so on little endian hosts a section
+is byte for byte the same as the slice holding it and can be read straight in.
+*/
+var littleEndianHost = func() bool {
+	probe := uint16(1)
+	return *(*byte)(unsafe.Pointer(&probe)) == 1
+}()
+
+func elementSize[T any]() int {
+	var zero T
+	return int(unsafe.Sizeof(zero))
+}
+
+/* The memory behind items as bytes, only meaningful on little endian hosts */
+func sectionBytes[T any](items []T) []byte {
+	if len(items) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&items[0])), len(items)*elementSize[T]())
+}
+
+/* Returns how many items were read in full */
+func readElements[T any](R *meshReader, section Section, items []T, first int) (int, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+	start := R.position()
+	size := elementSize[T]()
+
+	if !littleEndianHost {
+		if err := binary.Read(R, binary.LittleEndian, items); err != nil {
+			/* binary.Read keeps nothing from a short block so dont trust any of it */
+			return 0, R.fail(section, first, start, err)
+		}
+		return len(items), nil
+	}
+
+	read, err := io.ReadFull(R, sectionBytes(items))
+	if err != nil {
+		whole := read / size
+		return whole, R.fail(section, first+whole, start+int64(whole*size), err)
+	}
+	return len(items), nil
+}
+
+/* Writes the first count items in one go */
+func writeSection[T any](stream io.Writer, items []T, count int) error {
+	if count > len(items) {
+		return ErrCountMismatch
+	}
+	if !littleEndianHost {
+		return binary.Write(stream, binary.LittleEndian, items[:count])
+	}
+	_, err := stream.Write(sectionBytes(items[:count]))
+	return err
+}
@@ -0,0 +1,125 @@
+package mesh
+
+import "slices"
+
+/* How many of each thing a Clean call threw away */
+type CleanReport struct {
+	RepeatedIndexFaces int /* faces like {1,1,2} */
+	DegenerateFaces    int /* all three corners on a line or point */
+	DuplicateFaces     int /* same triangle and winding twice in one lod */
+	UnreferencedVerts  int
+}
+
+func (R CleanReport) Total() int {
+	return R.RepeatedIndexFaces + R.DegenerateFaces + R.DuplicateFaces + R.UnreferencedVerts
+}
+
+/* Rotated so the smallest index is first, keeps winding so flipped copies stay */
+func canonicalFace(face Face) Face {
+	switch {
+	case face.B < face.A && face.B < face.C:
+		return Face{face.B, face.C, face.A}
+	case face.C < face.A && face.C < face.B:
+		return Face{face.C, face.A, face.B}
+	default:
+		return face
+	}
+}
+
+func zeroArea(a, b, c [3]float32) bool {
+	ux, uy, uz := float64(b[0]-a[0]), float64(b[1]-a[1]), float64(b[2]-a[2])
+	vx, vy, vz := float64(c[0]-a[0]), float64(c[1]-a[1]), float64(c[2]-a[2])
+	cx, cy, cz := uy*vz-uz*vy, uz*vx-ux*vz, ux*vy-uy*vx
+	return cx*cx+cy*cy+cz*cz == 0
+}
+
+/*
+Works out which faces and verts survive. Duplicates are only looked for
+inside a lod level since lower levels are allowed to reuse faces.
+Faces pointing outside the vert list, or outside every lod level, are left
+for Validate / Repair.
+*/
+func cleanMarks(faces []Face, lods []uint32, numVerts int, position func(uint32) [3]float32, report *CleanReport) ([]bool, []bool) {
+	keepFace := slices.Repeat([]bool{true}, len(faces))
+	for _, lodRange := range lodRanges(lods, len(faces)) {
+		seen := make(map[Face]bool)
+		for i := lodRange[0]; i < lodRange[1]; i++ {
+			face := faces[i]
+			if !faceInRange(face, numVerts) {
+				continue
+			}
+			switch {
+			case face.A == face.B || face.B == face.C || face.A == face.C:
+				report.RepeatedIndexFaces++
+			case zeroArea(position(face.A), position(face.B), position(face.C)):
+				report.DegenerateFaces++
+			case seen[canonicalFace(face)]:
+				report.DuplicateFaces++
+			default:
+				seen[canonicalFace(face)] = true
+				continue
+			}
+			keepFace[i] = false
+		}
+	}
+
+	keepVert := usedVerts(numVerts, faces, keepFace)
+	for _, used := range keepVert {
+		if !used {
+			report.UnreferencedVerts++
+		}
+	}
+	return keepFace, keepVert
+}
+
+/* Returns a cleaned copy, lods subsets and envelopes are remapped to match */
+func (M *Mesh2NoRgba) Clean() (*Mesh2NoRgba, *CleanReport) {
+	report := CleanReport{}
+	keepFace, keepVert := cleanMarks(M.Faces, nil, len(M.Verts), func(i uint32) [3]float32 {
+		return [3]float32{M.Verts[i].Px, M.Verts[i].Py, M.Verts[i].Pz}
+	}, &report)
+	return M.filter(keepFace, keepVert), &report
+}
+
+func (M *Mesh2Rgba) Clean() (*Mesh2Rgba, *CleanReport) {
+	report := CleanReport{}
+	keepFace, keepVert := cleanMarks(M.Faces, nil, len(M.Verts), func(i uint32) [3]float32 {
+		return [3]float32{M.Verts[i].Px, M.Verts[i].Py, M.Verts[i].Pz}
+	}, &report)
+	return M.filter(keepFace, keepVert), &report
+}
+
+func (M *Mesh3) Clean() (*Mesh3, *CleanReport) {
+	report := CleanReport{}
+	keepFace, keepVert := cleanMarks(M.Faces, M.Lods, len(M.Verts), func(i uint32) [3]float32 {
+		return [3]float32{M.Verts[i].Px, M.Verts[i].Py, M.Verts[i].Pz}
+	}, &report)
+	return M.filter(keepFace, keepVert), &report
+}
+
+func (M *Mesh4) Clean() (*Mesh4, *CleanReport) {
+	report := CleanReport{}
+	keepFace, keepVert := cleanMarks(M.Faces, M.Lods, len(M.Verts), func(i uint32) [3]float32 {
+		return [3]float32{M.Verts[i].Px, M.Verts[i].Py, M.Verts[i].Pz}
+	}, &report)
+	return M.filter(keepFace, keepVert), &report
+}
+
+func Clean(mesh Mesh) (Mesh, *CleanReport, error) {
+	switch typed := mesh.(type) {
+	case *Mesh2NoRgba:
+		newMesh, report := typed.Clean()
+		return newMesh, report, nil
+	case *Mesh2Rgba:
+		newMesh, report := typed.Clean()
+		return newMesh, report, nil
+	case *Mesh3:
+		newMesh, report := typed.Clean()
+		return newMesh, report, nil
+	case *Mesh4:
+		newMesh, report := typed.Clean()
+		return newMesh, report, nil
+	default:
+		return nil, nil, ErrBadMeshVersion
+	}
+}
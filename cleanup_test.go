@@ -0,0 +1,59 @@
+package mesh_test
+
+import (
+	"github.com/MojaveMF/mesh"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	dirty := twoTriangles()
+	dirty.Verts = append(dirty.Verts, mesh.VertexModern{Px: 9})
+	dirty.Faces = []mesh.Face{
+		{A: 0, B: 1, C: 2},
+		{A: 1, B: 2, C: 0}, /* same as the first */
+		{A: 0, B: 2, C: 1}, /* flipped, should stay */
+		{A: 0, B: 0, C: 1},
+		{A: 1, B: 3, C: 4}, /* 1 and 3 are in the same spot */
+		{A: 3, B: 4, C: 5},
+	}
+	dirty.Lods = []uint32{0, 4, 6}
+	dirty.Header.NumLods = 3
+	dirty.Header.NumVerts = uint32(len(dirty.Verts))
+	dirty.Header.NumFaces = uint32(len(dirty.Faces))
+
+	cleaned, report := dirty.Clean()
+	expected := mesh.CleanReport{RepeatedIndexFaces: 1, DegenerateFaces: 1, DuplicateFaces: 1, UnreferencedVerts: 1}
+	if *report != expected {
+		t.Errorf("got report %+v", *report)
+	}
+	if len(cleaned.Faces) != 3 || len(cleaned.Verts) != 6 {
+		t.Errorf("got %d faces %d verts", len(cleaned.Faces), len(cleaned.Verts))
+	}
+	if cleaned.Lods[1] != 2 || cleaned.Lods[2] != 3 || cleaned.Header.NumLods != 3 {
+		t.Errorf("lods not remapped %v", cleaned.Lods)
+	}
+}
+
+func TestCleanV4(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+	cleaned, report := mesh4.Clean()
+	if len(cleaned.Faces) != len(mesh4.Faces)-report.RepeatedIndexFaces-report.DegenerateFaces-report.DuplicateFaces {
+		t.Errorf("report does not match removed faces %+v", *report)
+	}
+	if len(cleaned.Envelopes) != len(cleaned.Verts) {
+		t.Error("envelopes out of sync with verts")
+	}
+	if cleaned.MeshSubsets[0].FacesLength != cleaned.Lods[1] {
+		t.Errorf("subset no longer covers lod 0 %+v", cleaned.MeshSubsets[0])
+	}
+}
+
+func TestCleanOutsideLods(t *testing.T) {
+	/* Lods only cover the first face, the second is left alone */
+	partial := twoTriangles()
+	partial.Lods = []uint32{0, 1}
+	cleaned, report := partial.Clean()
+	if report.Total() != 0 || len(cleaned.Faces) != 2 || len(cleaned.Verts) != 6 {
+		t.Errorf("expected nothing removed got %+v with %d faces %d verts", *report, len(cleaned.Faces), len(cleaned.Verts))
+	}
+}
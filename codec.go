@@ -0,0 +1,350 @@
+package mesh
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrCodecExists  = errors.New("a codec with that name or version is already registered")
+	ErrBadCodec     = errors.New("codec is missing something it needs")
+	ErrUnknownCodec = errors.New("no codec matches the input")
+)
+
+/*
+A mesh format that can be found in the input and read or written. The built
+in versions are codecs too so anything registered works everywhere they do.
+*/
+type Codec struct {
+	Name string
+
+	/*
+		Picked by the codec and the line the file starts with, formats that are
+		not a mesh version leave both empty. Built in versions are single bit
+		flags, registered ones use any free value with more than one bit set so
+		they never turn up in a flag mask.
+	*/
+	Version     uint8
+	VersionLine string
+
+	/*
+		Built in version whose layout after the version line this shares, so
+		sniffing, streaming transcodes and policies treat it the same. 0 when
+		the layout is its own and meshes can only be decoded and encoded.
+	*/
+	Layout uint8
+
+	/* Gets up to the first 64 bytes, defaults to matching VersionLine */
+	Detect func(prefix []byte) bool
+
+	/* The stream starts after VersionLine when there is one, at the very start otherwise */
+	Decode func(stream io.Reader, opts DecodeOptions) (Mesh, error)
+
+	/* Writes the whole file including the version line */
+	Encode func(stream io.Writer, mesh Mesh) error
+}
+
+func (C *Codec) detect(prefix []byte) bool {
+	if C.Detect != nil {
+		return C.Detect(prefix)
+	}
+	return C.VersionLine != "" && strings.HasPrefix(string(prefix), C.VersionLine+"\n")
+}
+
+type codecRegistry struct {
+	lock   sync.RWMutex
+	codecs []Codec /* detection goes in registration order */
+}
+
+var registry codecRegistry
+
+/* Set up in init since the built in decoders look versions up in the registry themselves */
+func init() {
+	registry.codecs = builtinCodecs()
+}
+
+/* Oldest first */
+var builtinVersions = []uint8{MeshVersion1, MeshVersion1_01, MeshVersion2, MeshVersion3, MeshVersion3_01, MeshVersion4, MeshVersion4_1}
+
+func builtinVersion(version uint8) bool {
+	return slices.Contains(builtinVersions, version)
+}
+
+/* Adds a codec, it is safe to call while other goroutines decode */
+func RegisterCodec(codec Codec) error {
+	switch {
+	case codec.Name == "":
+		return fmt.Errorf("%w: name", ErrBadCodec)
+	case codec.Decode == nil && codec.Encode == nil:
+		return fmt.Errorf("%w: decoder or encoder", ErrBadCodec)
+	case codec.Detect == nil && codec.VersionLine == "":
+		return fmt.Errorf("%w: detect function or version line", ErrBadCodec)
+	case (codec.VersionLine == "") != (codec.Version == 0):
+		return fmt.Errorf("%w: a version line needs a version", ErrBadCodec)
+	case codec.Layout != 0 && (codec.Version == 0 || !builtinVersion(codec.Layout)):
+		return fmt.Errorf("%w: layout has to be a built in version", ErrBadCodec)
+	}
+
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	for _, existing := range registry.codecs {
+		if existing.Name == codec.Name || (codec.Version != 0 && existing.Version == codec.Version) ||
+			(codec.VersionLine != "" && existing.VersionLine == codec.VersionLine) {
+			return ErrCodecExists
+		}
+	}
+	if bits.OnesCount8(codec.Version) == 1 {
+		return fmt.Errorf("%w: single bit versions are kept for the built in ones", ErrBadCodec)
+	}
+	registry.codecs = append(registry.codecs, codec)
+	return nil
+}
+
+/* Every registered codec, built in ones first */
+func Codecs() []Codec {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	return append([]Codec(nil), registry.codecs...)
+}
+
+func findCodec(match func(*Codec) bool) (Codec, bool) {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	for i := range registry.codecs {
+		if match(&registry.codecs[i]) {
+			return registry.codecs[i], true
+		}
+	}
+	return Codec{}, false
+}
+
+func LookupCodec(name string) (Codec, bool) {
+	return findCodec(func(C *Codec) bool { return C.Name == name })
+}
+
+func codecForVersion(version uint8) (Codec, bool) {
+	return findCodec(func(C *Codec) bool { return version != 0 && C.Version == version })
+}
+
+/* First codec that claims the prefix */
+func DetectCodec(prefix []byte) (Codec, bool) {
+	return findCodec(func(C *Codec) bool { return C.detect(prefix) })
+}
+
+/*
+Decodes with whichever codec recognises the input and says which one it was.
+Seekable streams are put back before decoding, anything else is wrapped in a
+bufio.Reader so more than the mesh may be taken from it.
+*/
+func DecodeDetect(stream io.Reader, opts DecodeOptions) (Mesh, Codec, error) {
+	var prefix []byte
+	var err error
+	if seeker, ok := stream.(io.ReadSeeker); ok {
+		prefix, err = seekPrefix(seeker)
+	} else {
+		buffered, ok := stream.(*bufio.Reader)
+		if !ok {
+			buffered = bufio.NewReader(stream)
+			stream = buffered
+		}
+		if prefix, err = buffered.Peek(sniffSize); errors.Is(err, io.EOF) || errors.Is(err, bufio.ErrBufferFull) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, Codec{}, err
+	}
+
+	codec, ok := DetectCodec(prefix)
+	if !ok || codec.Decode == nil {
+		return nil, Codec{}, ErrUnknownCodec
+	}
+	if codec.Version == 0 {
+		decoded, err := codec.Decode(stream, opts)
+		return decoded, codec, err
+	}
+
+	/* Version lines go through the same path as DecodeMesh so offsets and limits line up */
+	reader := newMeshReader(stream, opts, 0)
+	decoded, _, err := decodeReader(reader, opts)
+	return decoded, codec, err
+}
+
+func seekPrefix(stream io.ReadSeeker) ([]byte, error) {
+	start, err := stream.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, sniffSize)
+	read, err := io.ReadFull(stream, prefix)
+	if _, seekErr := stream.Seek(start, io.SeekStart); seekErr != nil {
+		return nil, seekErr
+	}
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return prefix[:read], nil
+}
+
+/* Writes mesh with the codec registered under name */
+func EncodeCodec(name string, mesh Mesh, stream io.Writer) error {
+	codec, ok := LookupCodec(name)
+	if !ok || codec.Encode == nil {
+		return ErrUnknownCodec
+	}
+	return codec.Encode(stream, mesh)
+}
+
+/*
+Built in encoders take a Model so every conversion goes through one place,
+meshes already in the target layout are written as is. v3 and v4 share their
+verts faces and lods so they skip the copy, the result is the same as going
+through the Model. Types NewModel does not know fail with its error instead
+of converting some other way.
+*/
+func exportV1(mesh Mesh) (*Mesh1, error) {
+	model, err := NewModel(mesh)
+	if err != nil {
+		return nil, err
+	}
+	return model.ExportV1(), nil
+}
+
+func exportV2(mesh Mesh) (Mesh2, error) {
+	switch typed := mesh.(type) {
+	case *Mesh2Rgba:
+		return typed, nil
+	case *Mesh2NoRgba:
+		return typed, nil
+	}
+	model, err := NewModel(mesh)
+	if err != nil {
+		return nil, err
+	}
+	return model.ExportV2(), nil
+}
+
+func exportV3(mesh Mesh) (*Mesh3, error) {
+	switch typed := mesh.(type) {
+	case *Mesh3:
+		return typed, nil
+	case *Mesh4:
+		mesh3 := typed.ExportV3()
+		mesh3.Lods = sharedLods(typed.Lods, len(typed.Faces))
+		mesh3.Header.NumLods = uint16(len(mesh3.Lods))
+		return mesh3, nil
+	}
+	model, err := NewModel(mesh)
+	if err != nil {
+		return nil, err
+	}
+	return model.ExportV3(), nil
+}
+
+func exportV4(mesh Mesh) (*Mesh4, error) {
+	switch typed := mesh.(type) {
+	case *Mesh4:
+		return typed, nil
+	case *Mesh3:
+		mesh4 := typed.ExportV4()
+		mesh4.Lods = sharedLods(typed.Lods, len(typed.Faces))
+		mesh4.Header.NumVerts = uint32(len(typed.Verts))
+		mesh4.Header.NumFaces = uint32(len(typed.Faces))
+		mesh4.Header.NumLods = ushort(len(mesh4.Lods))
+		return mesh4, nil
+	}
+	model, err := NewModel(mesh)
+	if err != nil {
+		return nil, err
+	}
+	return model.ExportV4(), nil
+}
+
+/* Same lods the Model would export */
+func sharedLods(lods []uint32, numFaces int) []uint32 {
+	if len(lods) == 0 {
+		return singleLod(uint32(numFaces))
+	}
+	return lods
+}
+
+func builtinCodecs() []Codec {
+	return []Codec{
+		{Name: "roblox-1.00", Version: MeshVersion1, VersionLine: "version 1.00", Decode: decodeVersion1},
+		{Name: "roblox-1.01", Version: MeshVersion1_01, VersionLine: "version 1.01", Decode: decodeVersion1, Encode: func(stream io.Writer, mesh Mesh) error {
+			mesh1, err := exportV1(mesh)
+			if err != nil {
+				return err
+			}
+			return mesh1.Write(stream)
+		}},
+		{Name: "roblox-2.00", Version: MeshVersion2, VersionLine: "version 2.00", Decode: decodeVersion2, Encode: func(stream io.Writer, mesh Mesh) error {
+			mesh2, err := exportV2(mesh)
+			if err != nil {
+				return err
+			}
+			return mesh2.Write(stream)
+		}},
+		{Name: "roblox-3.00", Version: MeshVersion3, VersionLine: "version 3.00", Decode: decodeVersion3, Encode: func(stream io.Writer, mesh Mesh) error {
+			mesh3, err := exportV3(mesh)
+			if err != nil {
+				return err
+			}
+			return mesh3.Write(stream)
+		}},
+		{Name: "roblox-3.01", Version: MeshVersion3_01, VersionLine: "version 3.01", Decode: decodeVersion3, Encode: func(stream io.Writer, mesh Mesh) error {
+			mesh3, err := exportV3(mesh)
+			if err != nil {
+				return err
+			}
+			return mesh3.writeVersion(stream, "version 3.01\n")
+		}},
+		{Name: "roblox-4.00", Version: MeshVersion4, VersionLine: "version 4.00", Decode: decodeVersion4, Encode: func(stream io.Writer, mesh Mesh) error {
+			mesh4, err := exportV4(mesh)
+			if err != nil {
+				return err
+			}
+			return mesh4.Write(stream)
+		}},
+		{Name: "roblox-4.01", Version: MeshVersion4_1, VersionLine: "version 4.01", Decode: decodeVersion4, Encode: func(stream io.Writer, mesh Mesh) error {
+			mesh4, err := exportV4(mesh)
+			if err != nil {
+				return err
+			}
+			return mesh4.writeVersion(stream, "version 4.01\n")
+		}},
+	}
+}
+
+func decodeVersion1(stream io.Reader, opts DecodeOptions) (Mesh, error) {
+	return nil, ErrMeshVersion1
+}
+
+func decodeVersion2(stream io.Reader, opts DecodeOptions) (Mesh, error) {
+	stream2 := MeshStream2{stream}
+	return stream2.LoadMeshOptions(opts)
+}
+
+func decodeVersion3(stream io.Reader, opts DecodeOptions) (Mesh, error) {
+	stream3 := MeshStream3{stream}
+	mesh3, err := stream3.LoadMeshOptions(opts)
+	if mesh3 == nil {
+		return nil, err
+	}
+	return mesh3, err
+}
+
+func decodeVersion4(stream io.Reader, opts DecodeOptions) (Mesh, error) {
+	stream4 := MeshStream4{stream}
+	mesh4, err := stream4.LoadMeshOptions(opts)
+	if mesh4 == nil {
+		return nil, err
+	}
+	return mesh4, err
+}
@@ -0,0 +1,231 @@
+package mesh_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/MojaveMF/mesh"
+	"io"
+	"os"
+	"testing"
+)
+
+/* Registered versions cant be single bits */
+const (
+	meshVersion5 uint8 = 0x81
+	meshVersion6 uint8 = 0x82
+)
+
+/* A made up 5.00 that is laid out like 4.00, a 6.00 that holds a whole v3 file, and v4 behind a magic instead of a version line */
+var testCodecs = []mesh.Codec{
+	{
+		Name:        "test-5.00",
+		Version:     meshVersion5,
+		VersionLine: "version 5.00",
+		Layout:      mesh.MeshVersion4,
+		Decode: func(stream io.Reader, opts mesh.DecodeOptions) (mesh.Mesh, error) {
+			stream4 := mesh.MeshStream4{stream}
+			return stream4.LoadMeshOptions(opts)
+		},
+		Encode: func(stream io.Writer, encoded mesh.Mesh) error {
+			buffer := bytes.Buffer{}
+			if err := encoded.ExportV4().Write(&buffer); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(stream, "version 5.00\n"); err != nil {
+				return err
+			}
+			_, err := stream.Write(buffer.Bytes()[13:])
+			return err
+		},
+	},
+	{
+		Name:        "test-6.00",
+		Version:     meshVersion6,
+		VersionLine: "version 6.00",
+		Decode: func(stream io.Reader, opts mesh.DecodeOptions) (mesh.Mesh, error) {
+			return mesh.DecodeMeshOptions(stream, opts)
+		},
+		Encode: func(stream io.Writer, encoded mesh.Mesh) error {
+			if _, err := io.WriteString(stream, "version 6.00\n"); err != nil {
+				return err
+			}
+			return encoded.ExportV3().Write(stream)
+		},
+	},
+	{
+		Name:   "test-wrapped",
+		Detect: func(prefix []byte) bool { return bytes.HasPrefix(prefix, []byte("WRAP")) },
+		Decode: func(stream io.Reader, opts mesh.DecodeOptions) (mesh.Mesh, error) {
+			magic := make([]byte, 4)
+			if _, err := io.ReadFull(stream, magic); err != nil {
+				return nil, err
+			}
+			return mesh.DecodeMeshOptions(stream, opts)
+		},
+		Encode: func(stream io.Writer, encoded mesh.Mesh) error {
+			if _, err := io.WriteString(stream, "WRAP"); err != nil {
+				return err
+			}
+			return encoded.ExportV4().Write(stream)
+		},
+	},
+}
+
+func registerTestCodecs(t *testing.T) {
+	for _, codec := range testCodecs {
+		/* -count runs the tests again in the same process */
+		if err := mesh.RegisterCodec(codec); err != nil && !errors.Is(err, mesh.ErrCodecExists) {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDecoderRegisteredVersions(t *testing.T) {
+	registerTestCodecs(t)
+	output := bytes.Buffer{}
+	if err := mesh.NewEncoder(&output, mesh.EncodeVersion(meshVersion5)).Encode(decodeFile(t, "./testdata/output.v4")); err != nil {
+		t.Fatal(err)
+	}
+
+	/* 5.00 shares a bit with 1.00 but isnt a flag */
+	decoder := mesh.NewDecoder(bytes.NewReader(output.Bytes()), mesh.DecodeVersions(mesh.MeshVersion1))
+	if _, err := decoder.Decode(); !errors.Is(err, mesh.ErrVersionNotAllowed) {
+		t.Errorf("expected version not allowed got %v", err)
+	}
+	decoder = mesh.NewDecoder(bytes.NewReader(output.Bytes()), mesh.DecodeRegisteredVersions([]uint8{meshVersion5}))
+	if _, err := decoder.Decode(); err != nil {
+		t.Errorf("expected the registered version to decode got %v", err)
+	}
+}
+
+func TestCodecVersion(t *testing.T) {
+	registerTestCodecs(t)
+	decoded := decodeFile(t, "./testdata/output.v4")
+	expected := encode(t, decoded)
+
+	output := bytes.Buffer{}
+	if err := mesh.NewEncoder(&output, mesh.EncodeVersion(meshVersion5)).Encode(decoded); err != nil {
+		t.Fatal(err)
+	}
+	if line, err := mesh.MeshHeader(meshVersion5); err != nil || line != "version 5.00" {
+		t.Errorf("expected the registered version line got %q %v", line, err)
+	}
+	if version, err := mesh.MeshVersion(bytes.NewReader(output.Bytes())); err != nil || version != meshVersion5 {
+		t.Errorf("expected version 5 got %d %v", version, err)
+	}
+
+	decoded5, err := mesh.DecodeMesh(bytes.NewReader(output.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encode(t, decoded5), expected) {
+		t.Errorf("5.00 decodes to a different mesh")
+	}
+
+	transcoded := bytes.Buffer{}
+	if err := mesh.Transcode(bytes.NewReader(output.Bytes()), &transcoded, mesh.MeshVersion4); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(transcoded.Bytes(), expected) {
+		t.Errorf("5.00 transcodes to a different mesh")
+	}
+}
+
+func TestCodecDetect(t *testing.T) {
+	registerTestCodecs(t)
+	decoded := decodeFile(t, "./testdata/output.v4")
+	expected := encode(t, decoded)
+
+	wrapped := bytes.Buffer{}
+	if err := mesh.EncodeCodec("test-wrapped", decoded, &wrapped); err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []io.Reader{bytes.NewReader(wrapped.Bytes()), plainReader{bytes.NewReader(wrapped.Bytes())}} {
+		detected, codec, err := mesh.DecodeDetect(src, mesh.DecodeOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if codec.Name != "test-wrapped" || !bytes.Equal(encode(t, detected), expected) {
+			t.Errorf("expected the wrapped codec got %s", codec.Name)
+		}
+	}
+
+	data, err := os.ReadFile("./testdata/output.v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, codec, err := mesh.DecodeDetect(bytes.NewReader(data), mesh.DecodeOptions{}); err != nil || codec.Name != "roblox-3.00" {
+		t.Errorf("expected the built in 3.00 codec got %q %v", codec.Name, err)
+	}
+	if _, _, err := mesh.DecodeDetect(bytes.NewReader([]byte("not a mesh")), mesh.DecodeOptions{}); !errors.Is(err, mesh.ErrUnknownCodec) {
+		t.Errorf("expected no codec got %v", err)
+	}
+}
+
+func TestRegisterCodec(t *testing.T) {
+	builtin := mesh.Codecs()[0]
+	if builtin.Name != "roblox-1.00" || builtin.Encode != nil {
+		t.Errorf("expected 1.00 first and without an encoder got %s", builtin.Name)
+	}
+	if err := mesh.RegisterCodec(builtin); !errors.Is(err, mesh.ErrCodecExists) {
+		t.Errorf("expected a duplicate got %v", err)
+	}
+
+	for _, codec := range []mesh.Codec{
+		{Name: "no-decoder", VersionLine: "version 9.00", Version: meshVersion5},
+		{Name: "no-version", VersionLine: "version 9.00", Encode: testCodecs[0].Encode},
+		{Name: "flag-version", VersionLine: "version 9.00", Version: 1 << 7, Encode: testCodecs[0].Encode},
+		{Name: "bad-layout", VersionLine: "version 9.00", Version: 0x90, Layout: 0x85, Encode: testCodecs[0].Encode},
+		{Name: "undetectable", Encode: testCodecs[0].Encode},
+	} {
+		if err := mesh.RegisterCodec(codec); !errors.Is(err, mesh.ErrBadCodec) {
+			t.Errorf("%s: expected a bad codec got %v", codec.Name, err)
+		}
+	}
+}
+
+func TestCodecRegisteredVersions(t *testing.T) {
+	registerTestCodecs(t)
+	decoded := decodeFile(t, "./testdata/output.v4")
+
+	/* 5.00 shares the v4 layout so it converts like v4 does */
+	if _, ok := mesh.EncodeMeshVersion(decoded, meshVersion5).(*mesh.Mesh4); !ok {
+		t.Error("5.00 did not export as a Mesh4")
+	}
+	output := bytes.Buffer{}
+	if err := mesh.NewEncoder(&output, mesh.EncodeVersion(meshVersion5)).Encode(decoded); err != nil {
+		t.Fatal(err)
+	}
+	info, err := mesh.Sniff(bytes.NewReader(output.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != meshVersion5 || info.Header == nil || info.Size != int64(output.Len()) || info.NumBones != 5 {
+		t.Errorf("sniffed 5.00 as %+v", info)
+	}
+
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	converted, report := convert(t, mesh.Policy{SupportedVersions: []uint8{meshVersion5}}, bytes.NewReader(data))
+	if report.Output != meshVersion5 || report.Strategy != mesh.StrategyHeaderOnly || !bytes.Equal(converted, output.Bytes()) {
+		t.Errorf("v4 went to %d by %s", report.Output, report.Strategy)
+	}
+
+	/* 6.00 has its own layout, converting goes through the codec */
+	if _, ok := mesh.EncodeMeshVersion(decoded, meshVersion6).(*mesh.Mesh3); !ok {
+		t.Error("6.00 did not convert through its codec")
+	}
+	output.Reset()
+	if err := mesh.NewEncoder(&output, mesh.EncodeVersion(meshVersion6)).Encode(decoded); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := mesh.Sniff(bytes.NewReader(output.Bytes())); err != nil || info.Header != nil || info.Size != -1 {
+		t.Errorf("sniffed 6.00 as %+v %v", info, err)
+	}
+	_, report = convert(t, mesh.Policy{Supported: mesh.MeshVersion3}, bytes.NewReader(output.Bytes()))
+	if report.Input != meshVersion6 || report.Output != mesh.MeshVersion3 || report.Strategy != mesh.StrategyReencode {
+		t.Errorf("6.00 went to %d by %s", report.Output, report.Strategy)
+	}
+}
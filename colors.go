@@ -0,0 +1,135 @@
+package mesh
+
+/* What VertexNoRgba.Modern gives verts that never had a color, note the alpha of 0 */
+var DefaultColor = Color{255, 255, 255, 0}
+
+/* Same as Modern with a chosen color instead of DefaultColor */
+func (V *VertexNoRgba) ModernColor(color Color) VertexModern {
+	modern := V.Modern()
+	modern.R, modern.G, modern.B, modern.A = color.R, color.G, color.B, color.A
+	return modern
+}
+
+func (V *VertexV1) ModernColor(color Color) VertexModern {
+	modern := V.Modern()
+	modern.R, modern.G, modern.B, modern.A = color.R, color.G, color.B, color.A
+	return modern
+}
+
+/* Which vertex layout v2 output gets */
+type ColorLayout uint8
+
+const (
+	ColorLayoutKeep   ColorLayout = iota /* 36 bytes only when the mesh has no colors and no Fill was given */
+	ColorLayoutRgba                      /* always 40 byte verts */
+	ColorLayoutNoRgba                    /* always 36 byte verts, colors are dropped */
+	ColorLayoutAuto                      /* 36 bytes when every color is the fill color */
+)
+
+type ColorOptions struct {
+	/* Given to verts without colors when the output stores them, nil uses DefaultColor */
+	Fill   *Color
+	Layout ColorLayout
+}
+
+/* Counts are verts, anything Dropped cant be got back by decoding the output */
+type ColorReport struct {
+	Synthesized int  /* verts given the fill color */
+	Dropped     int  /* colors other than the fill color that were not written */
+	NoRgba      bool /* v2 was written with 36 byte verts */
+}
+
+func (R ColorReport) Lossy() bool {
+	return R.Dropped > 0
+}
+
+/* Whether mesh stores colors, and how many verts it has and how many of those differ from fill */
+func countColors(mesh Mesh, fill Color) (bool, int, int) {
+	differ := 0
+	count := func(verts []VertexModern) {
+		for i := range verts {
+			if (Color{verts[i].R, verts[i].G, verts[i].B, verts[i].A}) != fill {
+				differ++
+			}
+		}
+	}
+
+	switch typed := mesh.(type) {
+	case *Mesh2NoRgba:
+		return false, len(typed.Verts), 0
+	case *Mesh2Rgba:
+		count(typed.Verts)
+		return true, len(typed.Verts), differ
+	case *Mesh3:
+		count(typed.Verts)
+		return true, len(typed.Verts), differ
+	case *Mesh4:
+		count(typed.Verts)
+		return true, len(typed.Verts), differ
+	case *Model:
+		if !typed.Present.Has(AttributeColors) {
+			return false, len(typed.Positions), 0
+		}
+		for _, color := range typed.Colors {
+			if color != fill {
+				differ++
+			}
+		}
+		return true, len(typed.Positions), differ
+	default:
+		verts := mesh.ExportV4().Verts
+		count(verts)
+		return true, len(verts), differ
+	}
+}
+
+func noRgba(mesh Mesh) *Mesh2NoRgba {
+	switch typed := mesh.ExportV2().(type) {
+	case *Mesh2NoRgba:
+		return typed
+	case *Mesh2Rgba:
+		converted := Mesh2NoRgba{Header: typed.Header, Verts: make([]VertexNoRgba, len(typed.Verts)), Faces: typed.Faces}
+		converted.Header.VertexSize = VertexNoRgbaSize
+		for i := range typed.Verts {
+			converted.Verts[i] = typed.Verts[i].NoColor()
+		}
+		return &converted
+	default:
+		return nil
+	}
+}
+
+/*
+Gets mesh ready to be written as version with colors handled the way opts
+says and reports what that did. Writing the result with an Encoder set to
+the same version gives the file described by the report.
+*/
+func ApplyColors(mesh Mesh, version uint8, opts ColorOptions) (Mesh, ColorReport) {
+	fill := DefaultColor
+	if opts.Fill != nil {
+		fill = *opts.Fill
+	}
+	has, verts, differ := countColors(mesh, fill)
+	report := ColorReport{}
+
+	switch versionFamily(version) {
+	case MeshVersion1:
+		report.Dropped = differ
+		return mesh, report
+	case MeshVersion2:
+		switch {
+		case opts.Layout == ColorLayoutNoRgba, opts.Layout == ColorLayoutAuto && differ == 0,
+			opts.Layout == ColorLayoutKeep && !has && opts.Fill == nil:
+			report.Dropped, report.NoRgba = differ, true
+			return noRgba(mesh), report
+		}
+	}
+
+	if !has {
+		report.Synthesized = verts
+		if fill != DefaultColor || versionFamily(version) == MeshVersion2 {
+			mesh = withColor(mesh, fill)
+		}
+	}
+	return mesh, report
+}
@@ -0,0 +1,84 @@
+package mesh_test
+
+import (
+	"bytes"
+	"github.com/MojaveMF/mesh"
+	"testing"
+)
+
+func defaultColored() *mesh.Mesh3 {
+	colored := twoTriangles()
+	for i := range colored.Verts {
+		vertex := &colored.Verts[i]
+		vertex.R, vertex.G, vertex.B, vertex.A = mesh.DefaultColor.R, mesh.DefaultColor.G, mesh.DefaultColor.B, mesh.DefaultColor.A
+	}
+	return colored
+}
+
+func TestApplyColors(t *testing.T) {
+	opaque := mesh.Color{255, 255, 255, 255}
+	noRgba := mustDecode(t, noRgbaMesh())
+	rgba, painted := defaultColored(), defaultColored()
+	painted.Verts[0].R = 10
+
+	for _, test := range []struct {
+		name     string
+		mesh     mesh.Mesh
+		version  uint8
+		opts     mesh.ColorOptions
+		expected mesh.ColorReport
+	}{
+		{"norgba stays small", noRgba, mesh.MeshVersion2, mesh.ColorOptions{}, mesh.ColorReport{NoRgba: true}},
+		{"norgba filled", noRgba, mesh.MeshVersion2, mesh.ColorOptions{Fill: &opaque}, mesh.ColorReport{Synthesized: 6}},
+		{"norgba to v4", noRgba, mesh.MeshVersion4, mesh.ColorOptions{}, mesh.ColorReport{Synthesized: 6}},
+		{"default colors shrink", rgba, mesh.MeshVersion2, mesh.ColorOptions{Layout: mesh.ColorLayoutAuto}, mesh.ColorReport{NoRgba: true}},
+		{"painted colors stay", painted, mesh.MeshVersion2, mesh.ColorOptions{Layout: mesh.ColorLayoutAuto}, mesh.ColorReport{}},
+		{"painted colors dropped", painted, mesh.MeshVersion2, mesh.ColorOptions{Layout: mesh.ColorLayoutNoRgba}, mesh.ColorReport{Dropped: 1, NoRgba: true}},
+		{"painted to v1", painted, mesh.MeshVersion1_01, mesh.ColorOptions{}, mesh.ColorReport{Dropped: 1}},
+		{"forced rgba", noRgba, mesh.MeshVersion2, mesh.ColorOptions{Layout: mesh.ColorLayoutRgba}, mesh.ColorReport{Synthesized: 6}},
+	} {
+		output := bytes.Buffer{}
+		encoder := mesh.NewEncoder(&output, mesh.EncodeVersion(test.version), mesh.EncodeColors(test.opts))
+		if err := encoder.Encode(test.mesh); err != nil {
+			t.Fatal(err)
+		}
+		report := encoder.ColorReport()
+		if report != test.expected {
+			t.Errorf("%s: got %+v expected %+v", test.name, report, test.expected)
+		}
+		if report.Lossy() != (test.expected.Dropped > 0) {
+			t.Errorf("%s: lossy is wrong", test.name)
+		}
+		if test.version == mesh.MeshVersion2 {
+			info, err := mesh.Sniff(bytes.NewReader(output.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if small := info.Header.(*mesh.MeshHeader2).VertexSize == 36; small != report.NoRgba {
+				t.Errorf("%s: report says 36 byte verts is %v but the file disagrees", test.name, report.NoRgba)
+			}
+		}
+	}
+}
+
+func TestColorFill(t *testing.T) {
+	opaque := mesh.Color{255, 255, 255, 255}
+	output := bytes.Buffer{}
+	encoder := mesh.NewEncoder(&output, mesh.EncodeVersion(mesh.MeshVersion3), mesh.EncodeColor(opaque))
+	if err := encoder.Encode(mustDecode(t, noRgbaMesh())); err != nil {
+		t.Fatal(err)
+	}
+	for _, vertex := range mustDecode(t, output.Bytes()).ExportV3().Verts {
+		if vertex.A != 255 {
+			t.Fatalf("expected opaque verts got alpha %d", vertex.A)
+		}
+	}
+
+	vertex := mesh.VertexV1{Px: 1}
+	if modern := vertex.ModernColor(opaque); modern.A != 255 || modern.Px != 1 {
+		t.Errorf("expected an opaque vertex got %+v", modern)
+	}
+	if modern := vertex.Modern(); (mesh.Color{modern.R, modern.G, modern.B, modern.A}) != mesh.DefaultColor {
+		t.Errorf("Modern should still give DefaultColor")
+	}
+}
@@ -0,0 +1,217 @@
+package mesh
+
+import "slices"
+
+/*
+Helpers for dropping faces and verts while keeping lods, subsets and
+envelopes pointing at the right things. Everything works off prefix counts,
+prefix[i] being how many entries before i were kept. Picks work off the
+sorted indices kept instead so small pieces of a big mesh stay cheap.
+*/
+
+func prefixCount(keep []bool) []uint32 {
+	prefix := make([]uint32, len(keep)+1)
+	for i, kept := range keep {
+		prefix[i+1] = prefix[i]
+		if kept {
+			prefix[i+1]++
+		}
+	}
+	return prefix
+}
+
+/* Maps an offset through a prefix, anything past the end lands on the total */
+func remapOffset(prefix []uint32, offset uint32) uint32 {
+	if offset >= uint32(len(prefix)) {
+		return prefix[len(prefix)-1]
+	}
+	return prefix[offset]
+}
+
+func remapRange(prefix []uint32, begin, length uint32) (uint32, uint32) {
+	newBegin := remapOffset(prefix, begin)
+	return newBegin, remapOffset(prefix, begin+length) - newBegin
+}
+
+func keepItems[T any](items []T, keep []bool) []T {
+	out := make([]T, 0, len(items))
+	for i, item := range items {
+		if i < len(keep) && keep[i] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+/* Sorted indices of the entries a mask keeps */
+func keptIndices(keep []bool) []uint32 {
+	indices := make([]uint32, 0, len(keep))
+	for i, kept := range keep {
+		if kept {
+			indices = append(indices, uint32(i))
+		}
+	}
+	return indices
+}
+
+/* How many kept indices come before offset, the same as prefix[offset] */
+func keptBefore(kept []uint32, offset uint32) uint32 {
+	count, _ := slices.BinarySearch(kept, offset)
+	return uint32(count)
+}
+
+func keptRange(kept []uint32, begin, length uint32) (uint32, uint32) {
+	newBegin := keptBefore(kept, begin)
+	return newBegin, keptBefore(kept, begin+length) - newBegin
+}
+
+func pickItems[T any](items []T, kept []uint32) []T {
+	out := make([]T, 0, len(kept))
+	for _, i := range kept {
+		if int(i) < len(items) {
+			out = append(out, items[i])
+		}
+	}
+	return out
+}
+
+/* Sorted verts the kept faces use, faces that point past the vert list add nothing */
+func pickedVerts(numVerts int, faces []Face, kept []uint32) []uint32 {
+	verts := make([]uint32, 0, len(kept)*3)
+	for _, i := range kept {
+		if face := faces[i]; faceInRange(face, numVerts) {
+			verts = append(verts, face.A, face.B, face.C)
+		}
+	}
+	slices.Sort(verts)
+	return slices.Compact(verts)
+}
+
+/* Faces that point past the vert list are never marked */
+func usedVerts(numVerts int, faces []Face, keepFace []bool) []bool {
+	used := make([]bool, numVerts)
+	for i, face := range faces {
+		if !keepFace[i] || !faceInRange(face, numVerts) {
+			continue
+		}
+		used[face.A] = true
+		used[face.B] = true
+		used[face.C] = true
+	}
+	return used
+}
+
+func faceInRange(face Face, numVerts int) bool {
+	limit := uint32(numVerts)
+	return face.A < limit && face.B < limit && face.C < limit
+}
+
+func reindexFaces(faces []Face, keptVerts []uint32) {
+	for i := range faces {
+		faces[i].A = keptBefore(keptVerts, faces[i].A)
+		faces[i].B = keptBefore(keptVerts, faces[i].B)
+		faces[i].C = keptBefore(keptVerts, faces[i].C)
+	}
+}
+
+func remapLods(lods []uint32, facePrefix []uint32) []uint32 {
+	out := make([]uint32, len(lods))
+	for i, lod := range lods {
+		out[i] = remapOffset(facePrefix, lod)
+	}
+	return out
+}
+
+func pickLods(lods []uint32, keptFaces []uint32) []uint32 {
+	out := make([]uint32, len(lods))
+	for i, lod := range lods {
+		out[i] = keptBefore(keptFaces, lod)
+	}
+	return out
+}
+
+/* Compacted copies, the source mesh is left alone */
+func (M *Mesh2NoRgba) filter(keepFace, keepVert []bool) *Mesh2NoRgba {
+	return M.pick(keptIndices(keepFace), keptIndices(keepVert))
+}
+
+func (M *Mesh2Rgba) filter(keepFace, keepVert []bool) *Mesh2Rgba {
+	return M.pick(keptIndices(keepFace), keptIndices(keepVert))
+}
+
+func (M *Mesh3) filter(keepFace, keepVert []bool) *Mesh3 {
+	return M.pick(keptIndices(keepFace), keptIndices(keepVert))
+}
+
+func (M *Mesh4) filter(keepFace, keepVert []bool) *Mesh4 {
+	return M.pick(keptIndices(keepFace), keptIndices(keepVert))
+}
+
+/* Same as filter from sorted face and vert indices */
+func (M *Mesh2NoRgba) pick(faces, verts []uint32) *Mesh2NoRgba {
+	newMesh := Mesh2NoRgba{
+		Header: M.Header,
+		Verts:  pickItems(M.Verts, verts),
+		Faces:  pickItems(M.Faces, faces),
+	}
+	reindexFaces(newMesh.Faces, verts)
+	newMesh.Header.NumVerts = uint32(len(newMesh.Verts))
+	newMesh.Header.NumFaces = uint32(len(newMesh.Faces))
+	return &newMesh
+}
+
+func (M *Mesh2Rgba) pick(faces, verts []uint32) *Mesh2Rgba {
+	newMesh := Mesh2Rgba{
+		Header: M.Header,
+		Verts:  pickItems(M.Verts, verts),
+		Faces:  pickItems(M.Faces, faces),
+	}
+	reindexFaces(newMesh.Faces, verts)
+	newMesh.Header.NumVerts = uint32(len(newMesh.Verts))
+	newMesh.Header.NumFaces = uint32(len(newMesh.Faces))
+	return &newMesh
+}
+
+func (M *Mesh3) pick(faces, verts []uint32) *Mesh3 {
+	newMesh := Mesh3{
+		Header: M.Header,
+		Verts:  pickItems(M.Verts, verts),
+		Faces:  pickItems(M.Faces, faces),
+		Lods:   pickLods(M.Lods, faces),
+	}
+	reindexFaces(newMesh.Faces, verts)
+	newMesh.Header.NumVerts = uint32(len(newMesh.Verts))
+	newMesh.Header.NumFaces = uint32(len(newMesh.Faces))
+	newMesh.Header.NumLods = uint16(len(newMesh.Lods))
+	return &newMesh
+}
+
+/* Subsets that end up empty are dropped, bones are always kept */
+func (M *Mesh4) pick(faces, verts []uint32) *Mesh4 {
+	newMesh := Mesh4{
+		Header:      M.Header,
+		Verts:       pickItems(M.Verts, verts),
+		Envelopes:   pickItems(M.Envelopes, verts),
+		Faces:       pickItems(M.Faces, faces),
+		Lods:        pickLods(M.Lods, faces),
+		Bones:       append([]Bone{}, M.Bones...),
+		NameTable:   append([]byte{}, M.NameTable...),
+		MeshSubsets: make([]MeshSubset, 0, len(M.MeshSubsets)),
+	}
+	reindexFaces(newMesh.Faces, verts)
+
+	for _, subset := range M.MeshSubsets {
+		subset.VertsBegin, subset.VertsLength = keptRange(verts, subset.VertsBegin, subset.VertsLength)
+		subset.FacesBegin, subset.FacesLength = keptRange(faces, subset.FacesBegin, subset.FacesLength)
+		if subset.VertsLength == 0 && subset.FacesLength == 0 {
+			continue
+		}
+		newMesh.MeshSubsets = append(newMesh.MeshSubsets, subset)
+	}
+
+	newMesh.Header.NumVerts = uint32(len(newMesh.Verts))
+	newMesh.Header.NumFaces = uint32(len(newMesh.Faces))
+	newMesh.Header.NumLods = ushort(len(newMesh.Lods))
+	newMesh.Header.NumSubsets = ushort(len(newMesh.MeshSubsets))
+	return &newMesh
+}
@@ -0,0 +1,396 @@
+package mesh
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+/* Largest difference still counted as equal, zero means exact */
+type Tolerances struct {
+	Position float32
+	Normal   float32
+	UV       float32
+	Tangent  uint8 /* tangents are stored as int8 */
+	Color    uint8
+	Weight   uint8
+	Bone     float32 /* bone positions, rotations and culling */
+}
+
+type CompareOptions struct {
+	Tolerances
+
+	/*
+		Compares each LOD as a set of triangles so reordered verts and faces
+		still match, subset ranges are skipped since they depend on the order
+	*/
+	IgnoreOrder bool
+
+	/* Stop after this many differences, 0 finds them all */
+	Limit int
+}
+
+/* A and B are the values from each mesh */
+type Difference struct {
+	Section Section
+	Index   int /* element in the section, -1 when it is about the whole section */
+	Field   string
+	A, B    string
+}
+
+func (D Difference) String() string {
+	if D.Index < 0 {
+		return fmt.Sprintf("%s %s: %s != %s", D.Section, D.Field, D.A, D.B)
+	}
+	return fmt.Sprintf("%s[%d] %s: %s != %s", D.Section, D.Index, D.Field, D.A, D.B)
+}
+
+/* In the order found, sections go in file order */
+type Diff []Difference
+
+func (D Diff) String() string {
+	lines := make([]string, len(D))
+	for i, difference := range D {
+		lines[i] = difference.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+/* Whether a and b hold the same mesh whatever version they are */
+func Equal(a Mesh, b Mesh, opts CompareOptions) bool {
+	opts.Limit = 1
+	return len(Compare(a, b, opts)) == 0
+}
+
+/*
+Compares what the meshes hold rather than how they are stored, so header
+sizes and versions dont matter. NaN equals NaN.
+*/
+func Compare(a Mesh, b Mesh, opts CompareOptions) Diff {
+	comparer := comparer{opts: opts}
+	comparer.models(compareModel(a), compareModel(b))
+	return comparer.diff
+}
+
+func compareModel(mesh Mesh) *Model {
+	model, err := NewModel(mesh)
+	if err != nil {
+		return mesh.ExportV4().ToModel()
+	}
+	return model
+}
+
+type comparer struct {
+	opts CompareOptions
+	diff Diff
+}
+
+func (C *comparer) full() bool {
+	return C.opts.Limit > 0 && len(C.diff) >= C.opts.Limit
+}
+
+func (C *comparer) add(section Section, index int, field string, a any, b any) {
+	if !C.full() {
+		C.diff = append(C.diff, Difference{section, index, field, fmt.Sprint(a), fmt.Sprint(b)})
+	}
+}
+
+func closeFloat(a float32, b float32, tolerance float32) bool {
+	if math.IsNaN(float64(a)) || math.IsNaN(float64(b)) {
+		return math.IsNaN(float64(a)) && math.IsNaN(float64(b))
+	}
+	return a == b || float32(math.Abs(float64(a-b))) <= tolerance
+}
+
+func closeFloats(a []float32, b []float32, tolerance float32) bool {
+	for i := range a {
+		if !closeFloat(a[i], b[i], tolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+func closeInts[T int8 | uint8](a []T, b []T, tolerance uint8) bool {
+	for i := range a {
+		if max(int(a[i]), int(b[i]))-min(int(a[i]), int(b[i])) > int(tolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+/* Everything stored per vertex, skin included so reordered verts keep their weights */
+type compareVertex struct {
+	VertexModern
+	Skin SkinWeights
+}
+
+func (M *Model) compareVertex(i int) compareVertex {
+	vertex := compareVertex{VertexModern: M.vertex(i)}
+	if i < len(M.Skin) {
+		vertex.Skin = M.Skin[i]
+	}
+	return vertex
+}
+
+/* Name of the first field that differs, empty when they match */
+func (C *comparer) vertexField(a compareVertex, b compareVertex) (string, any, any) {
+	tolerances := C.opts.Tolerances
+	switch {
+	case !closeFloats([]float32{a.Px, a.Py, a.Pz}, []float32{b.Px, b.Py, b.Pz}, tolerances.Position):
+		return "position", Vector3{a.Px, a.Py, a.Pz}, Vector3{b.Px, b.Py, b.Pz}
+	case !closeFloats([]float32{a.Nx, a.Ny, a.Nz}, []float32{b.Nx, b.Ny, b.Nz}, tolerances.Normal):
+		return "normal", Vector3{a.Nx, a.Ny, a.Nz}, Vector3{b.Nx, b.Ny, b.Nz}
+	case !closeFloats([]float32{a.Tu, a.Tv}, []float32{b.Tu, b.Tv}, tolerances.UV):
+		return "uv", UV{a.Tu, a.Tv}, UV{b.Tu, b.Tv}
+	case !closeInts([]int8{a.Tx, a.Ty, a.Tz, a.Ts}, []int8{b.Tx, b.Ty, b.Tz, b.Ts}, tolerances.Tangent):
+		return "tangent", Tangent{a.Tx, a.Ty, a.Tz, a.Ts}, Tangent{b.Tx, b.Ty, b.Tz, b.Ts}
+	case !closeInts([]uint8{a.R, a.G, a.B, a.A}, []uint8{b.R, b.G, b.B, b.A}, tolerances.Color):
+		return "color", Color{a.R, a.G, a.B, a.A}, Color{b.R, b.G, b.B, b.A}
+	case a.Skin.Bones != b.Skin.Bones || !closeInts(a.Skin.Weights[:], b.Skin.Weights[:], tolerances.Weight):
+		return "skin", a.Skin, b.Skin
+	}
+	return "", nil, nil
+}
+
+func (C *comparer) models(a *Model, b *Model) {
+	if a.LodType != b.LodType {
+		C.add(SectionHeader, -1, "lod type", a.LodType, b.LodType)
+	}
+	if a.HighQualityLods != b.HighQualityLods {
+		C.add(SectionHeader, -1, "high quality lods", a.HighQualityLods, b.HighQualityLods)
+	}
+
+	if C.opts.IgnoreOrder {
+		C.triangleSets(a, b)
+	} else {
+		C.vertices(a, b)
+		C.faces(a, b)
+	}
+	C.bones(a, b)
+	C.subsets(a, b)
+}
+
+func (C *comparer) vertices(a *Model, b *Model) {
+	if len(a.Positions) != len(b.Positions) {
+		C.add(SectionVertices, -1, "count", len(a.Positions), len(b.Positions))
+		return
+	}
+	for i := range a.Positions {
+		if C.full() {
+			return
+		}
+		if field, valueA, valueB := C.vertexField(a.compareVertex(i), b.compareVertex(i)); field != "" {
+			C.add(SectionVertices, i, field, valueA, valueB)
+		}
+	}
+}
+
+func (C *comparer) faces(a *Model, b *Model) {
+	if len(a.Faces) != len(b.Faces) {
+		C.add(SectionFaces, -1, "count", len(a.Faces), len(b.Faces))
+	} else {
+		for i := range a.Faces {
+			if a.Faces[i] != b.Faces[i] {
+				C.add(SectionFaces, i, "indices", a.Faces[i], b.Faces[i])
+			}
+		}
+	}
+	if !slices.Equal(a.LodRanges(), b.LodRanges()) {
+		C.add(SectionLods, -1, "ranges", a.LodRanges(), b.LodRanges())
+	}
+}
+
+type compareTriangle struct {
+	face  int /* where it came from in mesh A or B */
+	verts [3]compareVertex
+}
+
+func compareVerts(a compareVertex, b compareVertex) int {
+	return cmp.Or(
+		cmp.Compare(a.Px, b.Px), cmp.Compare(a.Py, b.Py), cmp.Compare(a.Pz, b.Pz),
+		cmp.Compare(a.Nx, b.Nx), cmp.Compare(a.Ny, b.Ny), cmp.Compare(a.Nz, b.Nz),
+		cmp.Compare(a.Tu, b.Tu), cmp.Compare(a.Tv, b.Tv),
+		slices.Compare([]int8{a.Tx, a.Ty, a.Tz, a.Ts}, []int8{b.Tx, b.Ty, b.Tz, b.Ts}),
+		slices.Compare([]uint8{a.R, a.G, a.B, a.A}, []uint8{b.R, b.G, b.B, b.A}),
+		slices.Compare(a.Skin.Bones[:], b.Skin.Bones[:]),
+		slices.Compare(a.Skin.Weights[:], b.Skin.Weights[:]),
+	)
+}
+
+/* Rotated so the smallest vertex comes first, the winding is kept */
+func (M *Model) triangles(start uint32, end uint32) []compareTriangle {
+	triangles := make([]compareTriangle, 0, end-start)
+	for i := start; i < end; i++ {
+		face := M.Faces[i]
+		if !faceInRange(face, len(M.Positions)) {
+			continue
+		}
+		verts := [3]compareVertex{M.compareVertex(int(face.A)), M.compareVertex(int(face.B)), M.compareVertex(int(face.C))}
+		for compareVerts(verts[0], verts[1]) > 0 || compareVerts(verts[0], verts[2]) > 0 {
+			verts = [3]compareVertex{verts[1], verts[2], verts[0]}
+		}
+		triangles = append(triangles, compareTriangle{int(i), verts})
+	}
+	slices.SortFunc(triangles, func(a compareTriangle, b compareTriangle) int {
+		return cmp.Or(compareVerts(a.verts[0], b.verts[0]), compareVerts(a.verts[1], b.verts[1]), compareVerts(a.verts[2], b.verts[2]))
+	})
+	return triangles
+}
+
+/* Grid cell of a triangle's centre, a tolerance wide so a match is at most one cell over */
+type triangleCell [3]int64
+
+func (T compareTriangle) cell(size float32) triangleCell {
+	cell := triangleCell{}
+	for axis := range cell {
+		centre := float64(0)
+		for _, vertex := range T.verts {
+			centre += float64([3]float32{vertex.Px, vertex.Py, vertex.Pz}[axis]) / 3
+		}
+		value := math.Floor(centre / float64(size))
+		if math.IsNaN(value) {
+			value = 0
+		}
+		cell[axis] = int64(max(min(value, 1<<62), -(1 << 62)))
+	}
+	return cell
+}
+
+/* How far apart two triangles are when they match, rotating b since near ties can rotate differently */
+func (C *comparer) triangleDistance(a compareTriangle, b compareTriangle) (float64, bool) {
+	best, found := math.Inf(1), false
+	for rotation := range 3 {
+		distance := float64(0)
+		for corner := range a.verts {
+			vertexA, vertexB := a.verts[corner], b.verts[(corner+rotation)%3]
+			if field, _, _ := C.vertexField(vertexA, vertexB); field != "" {
+				distance = math.Inf(1)
+				break
+			}
+			distance += math.Abs(float64(vertexA.Px-vertexB.Px)) + math.Abs(float64(vertexA.Py-vertexB.Py)) + math.Abs(float64(vertexA.Pz-vertexB.Pz))
+		}
+		if distance <= best && !math.IsInf(distance, 1) {
+			best, found = distance, true
+		}
+	}
+	return best, found
+}
+
+/* Each triangle in a takes the nearest unmatched one in b from the cells round it, the rest are left to report */
+func (C *comparer) matchTriangles(trianglesA []compareTriangle, trianglesB []compareTriangle) ([]compareTriangle, []compareTriangle) {
+	size := cmp.Or(C.opts.Position, 1)
+	cells := map[triangleCell][]int{}
+	for i, triangle := range trianglesB {
+		cell := triangle.cell(size)
+		cells[cell] = append(cells[cell], i)
+	}
+
+	matched := make([]bool, len(trianglesB))
+	leftA := []compareTriangle{}
+	for _, triangle := range trianglesA {
+		cell, nearest, best := triangle.cell(size), -1, math.Inf(1)
+		for dx := int64(-1); dx <= 1; dx++ {
+			for dy := int64(-1); dy <= 1; dy++ {
+				for dz := int64(-1); dz <= 1; dz++ {
+					for _, i := range cells[triangleCell{cell[0] + dx, cell[1] + dy, cell[2] + dz}] {
+						if matched[i] {
+							continue
+						}
+						if distance, ok := C.triangleDistance(triangle, trianglesB[i]); ok && (nearest < 0 || distance < best) {
+							nearest, best = i, distance
+						}
+					}
+				}
+			}
+		}
+		if nearest < 0 {
+			leftA = append(leftA, triangle)
+			continue
+		}
+		matched[nearest] = true
+	}
+
+	leftB := []compareTriangle{}
+	for i, triangle := range trianglesB {
+		if !matched[i] {
+			leftB = append(leftB, triangle)
+		}
+	}
+	return leftA, leftB
+}
+
+func (C *comparer) triangleSets(a *Model, b *Model) {
+	levelsA, levelsB := a.LodRanges(), b.LodRanges()
+	if len(levelsA) != len(levelsB) {
+		C.add(SectionLods, -1, "levels", len(levelsA), len(levelsB))
+		return
+	}
+	for level := range levelsA {
+		trianglesA := a.triangles(levelsA[level][0], levelsA[level][1])
+		trianglesB := b.triangles(levelsB[level][0], levelsB[level][1])
+		if len(trianglesA) != len(trianglesB) {
+			C.add(SectionFaces, -1, fmt.Sprintf("triangles in lod %d", level), len(trianglesA), len(trianglesB))
+			continue
+		}
+		trianglesA, trianglesB = C.matchTriangles(trianglesA, trianglesB)
+		for i := range trianglesA {
+			if C.full() {
+				return
+			}
+			for corner := range trianglesA[i].verts {
+				field, valueA, valueB := C.vertexField(trianglesA[i].verts[corner], trianglesB[i].verts[corner])
+				if field != "" {
+					C.add(SectionFaces, trianglesA[i].face, fmt.Sprintf("vertex %d %s", corner, field), valueA, valueB)
+					break
+				}
+			}
+		}
+	}
+}
+
+func (C *comparer) bones(a *Model, b *Model) {
+	if len(a.Bones) != len(b.Bones) {
+		C.add(SectionBones, -1, "count", len(a.Bones), len(b.Bones))
+		return
+	}
+	tolerance := C.opts.Bone
+	for i := range a.Bones {
+		boneA, boneB := &a.Bones[i], &b.Bones[i]
+		switch {
+		case boneA.Name != boneB.Name:
+			C.add(SectionNames, i, "name", boneA.Name, boneB.Name)
+		case boneA.Parent != boneB.Parent:
+			C.add(SectionBones, i, "parent", boneA.Parent, boneB.Parent)
+		case boneA.LodParent != boneB.LodParent:
+			C.add(SectionBones, i, "lod parent", boneA.LodParent, boneB.LodParent)
+		case !closeFloat(boneA.Culling, boneB.Culling, tolerance):
+			C.add(SectionBones, i, "culling", boneA.Culling, boneB.Culling)
+		case !closeFloats(boneA.Rotation[:], boneB.Rotation[:], tolerance):
+			C.add(SectionBones, i, "rotation", boneA.Rotation, boneB.Rotation)
+		case !closeFloats([]float32{boneA.Position.X, boneA.Position.Y, boneA.Position.Z}, []float32{boneB.Position.X, boneB.Position.Y, boneB.Position.Z}, tolerance):
+			C.add(SectionBones, i, "position", boneA.Position, boneB.Position)
+		}
+	}
+}
+
+func (C *comparer) subsets(a *Model, b *Model) {
+	if len(a.Subsets) != len(b.Subsets) {
+		C.add(SectionSubsets, -1, "count", len(a.Subsets), len(b.Subsets))
+		return
+	}
+	for i := range a.Subsets {
+		subsetA, subsetB := &a.Subsets[i], &b.Subsets[i]
+		rangeA := [4]uint32{subsetA.FacesBegin, subsetA.FacesLength, subsetA.VertsBegin, subsetA.VertsLength}
+		rangeB := [4]uint32{subsetB.FacesBegin, subsetB.FacesLength, subsetB.VertsBegin, subsetB.VertsLength}
+		switch {
+		case !slices.Equal(subsetA.Bones, subsetB.Bones):
+			C.add(SectionSubsets, i, "bones", subsetA.Bones, subsetB.Bones)
+		case !C.opts.IgnoreOrder && rangeA != rangeB:
+			C.add(SectionSubsets, i, "ranges", rangeA, rangeB)
+		}
+	}
+}
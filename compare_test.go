@@ -0,0 +1,131 @@
+package mesh_test
+
+import (
+	"bytes"
+	"github.com/MojaveMF/mesh"
+	"math"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mesh4 := mustDecode(t, data)
+	newer := mustDecode(t, append([]byte("version 4.01\n"), data[13:]...))
+	if diff := mesh.Compare(mesh4, newer, mesh.CompareOptions{}); len(diff) != 0 {
+		t.Errorf("expected 4.00 and 4.01 to match got\n%s", diff)
+	}
+
+	/* Header sizes and versions differ but the v2 and v3 copies hold the same thing */
+	mesh2 := twoTriangles().ExportV2()
+	if !mesh.Equal(twoTriangles(), mesh2, mesh.CompareOptions{}) {
+		t.Errorf("expected v3 and v2 copies to match")
+	}
+
+	diff := mesh.Compare(mesh4, mesh4.ExportV2(), mesh.CompareOptions{Limit: 3})
+	if len(diff) != 3 {
+		t.Fatalf("expected the limit to hold got %d", len(diff))
+	}
+	if diff[0].Section != mesh.SectionHeader && diff[0].Section != mesh.SectionVertices {
+		t.Errorf("expected differences in file order got\n%s", diff)
+	}
+}
+
+func TestCompareTolerance(t *testing.T) {
+	a, b := twoTriangles(), twoTriangles()
+	b.Verts[1].Px += 1e-5
+	b.Verts[2].R = 2
+
+	diff := mesh.Compare(a, b, mesh.CompareOptions{})
+	expected := mesh.Diff{
+		{Section: mesh.SectionVertices, Index: 1, Field: "position", A: "{1 0 0}", B: "{1.00001 0 0}"},
+		{Section: mesh.SectionVertices, Index: 2, Field: "color", A: "{0 0 0 0}", B: "{2 0 0 0}"},
+	}
+	if !slices.Equal(diff, expected) {
+		t.Errorf("unexpected diff\n%s", diff)
+	}
+	tolerant := mesh.CompareOptions{Tolerances: mesh.Tolerances{Position: 1e-4, Color: 2}}
+	if !mesh.Equal(a, b, tolerant) {
+		t.Errorf("expected the tolerances to cover it got\n%s", mesh.Compare(a, b, tolerant))
+	}
+
+	a.Verts[0].Nx, b.Verts[0].Nx = float32(math.NaN()), float32(math.NaN())
+	if !mesh.Equal(a, b, tolerant) {
+		t.Errorf("expected NaN to equal NaN")
+	}
+	b.Verts[0].Nx = 0
+	if mesh.Equal(a, b, tolerant) {
+		t.Errorf("expected NaN to differ from a number")
+	}
+}
+
+func TestCompareIgnoreOrder(t *testing.T) {
+	a := decodeFile(t, "./testdata/output.v3").(*mesh.Mesh3)
+
+	/* Reverse the verts and move each face's indices round without changing winding */
+	b := *a
+	count := uint32(len(a.Verts))
+	b.Verts = slices.Clone(a.Verts)
+	slices.Reverse(b.Verts)
+	b.Faces = make([]mesh.Face, len(a.Faces))
+	for i, face := range a.Faces {
+		b.Faces[i] = mesh.Face{A: count - 1 - face.B, B: count - 1 - face.C, C: count - 1 - face.A}
+	}
+	levels := b.ExportV4().ToModel().LodRanges()
+	for _, level := range levels {
+		slices.Reverse(b.Faces[level[0]:level[1]])
+	}
+
+	if mesh.Equal(a, &b, mesh.CompareOptions{}) {
+		t.Errorf("expected reordered meshes to differ in order")
+	}
+	if diff := mesh.Compare(a, &b, mesh.CompareOptions{IgnoreOrder: true, Limit: 5}); len(diff) != 0 {
+		t.Errorf("expected the same triangles got\n%s", diff)
+	}
+
+	/* Flipping a triangle's winding is a real difference */
+	b.Faces[0].B, b.Faces[0].C = b.Faces[0].C, b.Faces[0].B
+	if mesh.Equal(a, &b, mesh.CompareOptions{IgnoreOrder: true}) {
+		t.Errorf("expected a flipped triangle to be found")
+	}
+}
+
+func TestCompareIgnoreOrderTolerance(t *testing.T) {
+	a := twoTriangles()
+	a.Verts = []mesh.VertexModern{
+		{Px: 0}, {Px: 1}, {Py: 1},
+		{Px: 0.00005, Pz: 1}, {Px: 2, Pz: 1}, {Py: 2, Pz: 1},
+	}
+
+	/* Nudging the first triangle within tolerance puts it after the second when sorted */
+	b := *a
+	b.Verts = slices.Clone(a.Verts)
+	b.Verts[0].Px = 0.0001
+	if mesh.Equal(a, &b, mesh.CompareOptions{IgnoreOrder: true}) {
+		t.Errorf("expected the nudged triangle to differ exactly")
+	}
+	if diff := mesh.Compare(a, &b, mesh.CompareOptions{IgnoreOrder: true, Tolerances: mesh.Tolerances{Position: 0.001}}); len(diff) != 0 {
+		t.Errorf("expected the same triangles within tolerance got\n%s", diff)
+	}
+
+	/* Past the tolerance it still shows up */
+	b.Verts[0].Px = 0.01
+	if mesh.Equal(a, &b, mesh.CompareOptions{IgnoreOrder: true, Tolerances: mesh.Tolerances{Position: 0.001}}) {
+		t.Errorf("expected a triangle past the tolerance to differ")
+	}
+}
+
+func TestCompareRoundTrip(t *testing.T) {
+	decoded := decodeFile(t, "./testdata/output.v4")
+	output := bytes.Buffer{}
+	if err := mesh.NewEncoder(&output, mesh.EncodeVersion(mesh.MeshVersion4_1)).Encode(decoded); err != nil {
+		t.Fatal(err)
+	}
+	if diff := mesh.Compare(decoded, mustDecode(t, output.Bytes()), mesh.CompareOptions{}); len(diff) != 0 {
+		t.Errorf("round trip changed the mesh\n%s", diff)
+	}
+}
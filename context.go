@@ -0,0 +1,99 @@
+package mesh
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+/* Bytes read or written between checks on the context */
+const contextChunk = 64 << 10
+
+/*
+Returned when a context ends a write part way through, Written is how many
+bytes of the mesh made it to the stream. Unwraps to ctx.Err().
+*/
+type EncodeError struct {
+	Version uint8
+	Written int64
+	Err     error
+}
+
+func (E *EncodeError) Error() string {
+	version, err := MeshHeader(E.Version)
+	if err != nil {
+		version = "unknown version"
+	}
+	return fmt.Sprintf("mesh %s: stopped after %d bytes: %s", version, E.Written, E.Err)
+}
+
+func (E *EncodeError) Unwrap() error {
+	return E.Err
+}
+
+/*
+Same as DecodeMesh but gives up once ctx is done, the returned DecodeError
+says which section and element it got to.
+*/
+func DecodeMeshContext(ctx context.Context, stream io.Reader) (Mesh, error) {
+	return DecodeMeshContextOptions(ctx, stream, DecodeOptions{Limits: DefaultLimits})
+}
+
+func DecodeMeshContextOptions(ctx context.Context, stream io.Reader, opts DecodeOptions) (Mesh, error) {
+	reader := newMeshReader(stream, opts, 0)
+	reader.withContext(ctx)
+	decoded, _, err := decodeReader(reader, opts)
+	return decoded, err
+}
+
+/* Writes mesh as its own version, stopping with an *EncodeError once ctx is done */
+func WriteContext(ctx context.Context, mesh Mesh, stream io.Writer) error {
+	version := ownVersion(mesh)
+	return writeVersion(mesh, version, contextStream(ctx, stream, version))
+}
+
+/* Contexts that can never be cancelled are dropped so plain decodes pay nothing */
+func (R *meshReader) withContext(ctx context.Context) {
+	if ctx.Done() != nil {
+		R.ctx = ctx
+	}
+}
+
+func (R *meshReader) cancelled() error {
+	if R.ctx == nil {
+		return nil
+	}
+	return R.ctx.Err()
+}
+
+/* Checks ctx before every chunk it passes on */
+type contextWriter struct {
+	ctx     context.Context
+	stream  io.Writer
+	version uint8
+	written int64
+}
+
+func contextStream(ctx context.Context, stream io.Writer, version uint8) io.Writer {
+	if ctx.Done() == nil {
+		return stream
+	}
+	return &contextWriter{ctx: ctx, stream: stream, version: version}
+}
+
+func (W *contextWriter) Write(data []byte) (int, error) {
+	total := 0
+	for len(data) > 0 {
+		if err := W.ctx.Err(); err != nil {
+			return total, &EncodeError{W.version, W.written, err}
+		}
+		written, err := W.stream.Write(data[:min(len(data), contextChunk)])
+		total += written
+		W.written += int64(written)
+		if err != nil {
+			return total, err
+		}
+		data = data[written:]
+	}
+	return total, nil
+}
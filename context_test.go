@@ -0,0 +1,107 @@
+package mesh_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/MojaveMF/mesh"
+	"io"
+	"os"
+	"testing"
+)
+
+/* Cancels once after bytes have gone through */
+type cancelAfter struct {
+	bytes  int
+	cancel context.CancelFunc
+}
+
+func (C *cancelAfter) count(read int) {
+	C.bytes -= read
+	if C.bytes <= 0 {
+		C.cancel()
+	}
+}
+
+type cancelReader struct {
+	io.Reader
+	*cancelAfter
+}
+
+func (R cancelReader) Read(data []byte) (int, error) {
+	read, err := R.Reader.Read(data)
+	R.count(read)
+	return read, err
+}
+
+type cancelWriter struct {
+	io.Writer
+	*cancelAfter
+}
+
+func (W cancelWriter) Write(data []byte) (int, error) {
+	written, err := W.Writer.Write(data)
+	W.count(written)
+	return written, err
+}
+
+func TestDecodeMeshContext(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := encode(t, decodeFile(t, "./testdata/output.v4"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	decoded, err := mesh.DecodeMeshContext(ctx, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encode(t, decoded), expected) {
+		t.Errorf("decode with a live context differs")
+	}
+
+	/* Stops inside the vertices, well past the header */
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	src := cancelReader{plainReader{bytes.NewReader(data)}, &cancelAfter{40 << 10, cancel}}
+	_, err = mesh.NewDecoder(src).DecodeContext(ctx)
+	var decodeErr *mesh.DecodeError
+	if !errors.Is(err, context.Canceled) || !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a cancelled decode error got %v", err)
+	}
+	if decodeErr.Section != mesh.SectionVertices || decodeErr.Index <= 0 || decodeErr.Offset <= 0 {
+		t.Errorf("expected progress into the vertices got %s", decodeErr)
+	}
+
+	if _, err := mesh.DecodeMeshContext(ctx, bytes.NewReader(data)); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected an already cancelled context to stop the decode got %v", err)
+	}
+}
+
+func TestWriteContext(t *testing.T) {
+	decoded := decodeFile(t, "./testdata/output.v4")
+	expected := encode(t, decoded)
+
+	output := bytes.Buffer{}
+	if err := mesh.WriteContext(context.Background(), decoded, &output); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(output.Bytes(), expected) {
+		t.Errorf("write with a background context differs")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	output.Reset()
+	dst := cancelWriter{&output, &cancelAfter{100 << 10, cancel}}
+	err := mesh.NewEncoder(dst, mesh.EncodeVersion(mesh.MeshVersion3)).EncodeContext(ctx, decoded)
+	var encodeErr *mesh.EncodeError
+	if !errors.Is(err, context.Canceled) || !errors.As(err, &encodeErr) {
+		t.Fatalf("expected a cancelled encode error got %v", err)
+	}
+	if encodeErr.Version != mesh.MeshVersion3 || encodeErr.Written != int64(output.Len()) || output.Len() >= len(expected) {
+		t.Errorf("unexpected progress %s with %d bytes written", encodeErr, output.Len())
+	}
+}
@@ -0,0 +1,63 @@
+package mesh
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+/* Every mesh file starts with a 13 byte "version x.xx\n" line */
+const versionLineSize = 13
+
+/*
+Points at where decoding broke, Offset is from the start of the file.
+Index is the element in Section or -1 when the section itself failed.
+*/
+type DecodeError struct {
+	Version uint8
+	Section Section
+	Index   int
+	Offset  int64
+	Err     error
+}
+
+func (E *DecodeError) Error() string {
+	version, err := MeshHeader(E.Version)
+	if err != nil {
+		version = "unknown version"
+	}
+	if E.Index < 0 {
+		return fmt.Sprintf("mesh %s: %s at byte %d: %s", version, E.Section, E.Offset, E.Err)
+	}
+	return fmt.Sprintf("mesh %s: %s[%d] at byte %d: %s", version, E.Section, E.Index, E.Offset, E.Err)
+}
+
+func (E *DecodeError) Unwrap() error {
+	return E.Err
+}
+
+/* Where the next byte comes from in the whole file */
+func (R *meshReader) position() int64 {
+	return R.base + R.offset
+}
+
+/* A section running out early is never a clean EOF */
+func (R *meshReader) fail(section Section, index int, offset int64, err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	var existing *DecodeError
+	if errors.As(err, &existing) {
+		return err
+	}
+	return &DecodeError{R.version, section, index, offset, err}
+}
+
+func (R *meshReader) readHeader(header any) error {
+	start := R.position()
+	if err := binary.Read(R, binary.LittleEndian, header); err != nil {
+		return R.fail(SectionHeader, -1, start, err)
+	}
+	return nil
+}
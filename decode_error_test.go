@@ -0,0 +1,61 @@
+package mesh_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/MojaveMF/mesh"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestDecodeErrorOffset(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	/* version line, header, verts and envelopes come before faces */
+	facesStart := 13 + 24 + 1880*40 + 1880*8
+	truncated := data[:facesStart+10*12+5]
+
+	_, err = mesh.DecodeMesh(io.MultiReader(bytes.NewReader(truncated)))
+	decodeErr := &mesh.DecodeError{}
+	if !errors.As(err, &decodeErr) {
+		t.Errorf("expected a DecodeError got %v", err)
+		return
+	}
+	if decodeErr.Section != mesh.SectionFaces || decodeErr.Index != 10 || decodeErr.Offset != int64(facesStart+10*12) {
+		t.Errorf("wrong location %+v", decodeErr)
+	}
+	if decodeErr.Version != mesh.MeshVersion4 || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("wrong version or cause: %s", err)
+	}
+}
+
+func TestDecodeErrorNameTable(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	/* Cut off inside the name table, which used to be ignored */
+	truncated := data[:len(data)-72-10]
+
+	file := bytes.NewReader(truncated)
+	file.Seek(13, io.SeekStart)
+	stream := mesh.MeshStream4{io.MultiReader(file)}
+	_, err = stream.LoadMesh()
+	decodeErr := &mesh.DecodeError{}
+	if !errors.As(err, &decodeErr) || decodeErr.Section != mesh.SectionNames {
+		t.Errorf("expected name table error got %v", err)
+	}
+
+	/* Seekable input knows it is short before reading anything */
+	file.Seek(13, io.SeekStart)
+	stream = mesh.MeshStream4{file}
+	if _, err := stream.LoadMesh(); !errors.Is(err, mesh.ErrLimitExceeded) || !errors.As(err, &decodeErr) {
+		t.Errorf("expected input size error got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package mesh
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrVersionNotAllowed = errors.New("mesh version is not allowed by the decoder")
+
+type Compression uint8
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionZlib
+)
+
+/* Holds the settings for every mesh read from one stream */
+type Decoder struct {
+	stream      io.Reader
+	opts        DecodeOptions
+	versions    uint8 /* MeshVersion flags or'd together, 0 allows all */
+	registered  []uint8
+	color       *Color
+	compression Compression
+	err         error /* set when the compressed stream could not be opened */
+}
+
+type DecoderOption func(*Decoder)
+
+func NewDecoder(stream io.Reader, opts ...DecoderOption) *Decoder {
+	decoder := Decoder{stream: stream, opts: DecodeOptions{Limits: DefaultLimits}}
+	for _, opt := range opts {
+		opt(&decoder)
+	}
+
+	switch decoder.compression {
+	case CompressionGzip:
+		decoder.stream, decoder.err = gzip.NewReader(stream)
+	case CompressionZlib:
+		decoder.stream, decoder.err = zlib.NewReader(stream)
+	}
+	return &decoder
+}
+
+/* Any of the MeshVersion flags or'd together, e.g. MeshVersion4 | MeshVersion4_1 */
+func DecodeVersions(versions uint8) DecoderOption {
+	return func(D *Decoder) {
+		D.versions = versions
+	}
+}
+
+/* Registered versions aren't flags so they're listed, either option set means only what's listed is allowed */
+func DecodeRegisteredVersions(versions []uint8) DecoderOption {
+	return func(D *Decoder) {
+		D.registered = versions
+	}
+}
+
+func DecodeLimits(limits Limits) DecoderOption {
+	return func(D *Decoder) {
+		D.opts.Limits = limits
+	}
+}
+
+func DecodeLenient(lenient bool) DecoderOption {
+	return func(D *Decoder) {
+		D.opts.Lenient = lenient
+	}
+}
+
+/* Meshes without colors come back colored this way instead of staying 36 byte v2 */
+func DecodeColor(color Color) DecoderOption {
+	return func(D *Decoder) {
+		D.color = &color
+	}
+}
+
+/* Limits apply to the decompressed bytes */
+func DecodeCompression(compression Compression) DecoderOption {
+	return func(D *Decoder) {
+		D.compression = compression
+	}
+}
+
+/* Reads the next mesh, partial meshes from lenient decodes come back with their error */
+func (D *Decoder) Decode() (Mesh, error) {
+	return D.DecodeContext(context.Background())
+}
+
+/* Stops once ctx is done, the DecodeError says how far it got */
+func (D *Decoder) DecodeContext(ctx context.Context) (Mesh, error) {
+	decoded, _, err := D.decode(ctx)
+	return decoded, err
+}
+
+func (D *Decoder) DecodeModel() (*Model, error) {
+	return D.DecodeModelContext(context.Background())
+}
+
+func (D *Decoder) DecodeModelContext(ctx context.Context) (*Model, error) {
+	decoded, version, err := D.decode(ctx)
+	if decoded == nil {
+		return nil, err
+	}
+	model, modelErr := NewModel(decoded)
+	if modelErr != nil {
+		return nil, modelErr
+	}
+	model.Version = version
+	return model, err
+}
+
+func (D *Decoder) decode(ctx context.Context) (Mesh, uint8, error) {
+	if D.err != nil {
+		return nil, 0, D.err
+	}
+	reader := newMeshReader(D.stream, D.opts, 0)
+	reader.withContext(ctx)
+	start := reader.position()
+	version, err := MeshVersion(reader)
+	if err != nil {
+		return nil, 0, &DecodeError{0, SectionHeader, -1, start, err}
+	}
+	reader.version = version
+	if !allowed(D.versions, D.registered, version) {
+		return nil, 0, &DecodeError{version, SectionHeader, -1, start, ErrVersionNotAllowed}
+	}
+
+	decoded, err := decodeMeshOptions(reader, version, D.opts)
+	if decoded != nil && D.color != nil {
+		decoded = withColor(decoded, *D.color)
+	}
+	return decoded, version, err
+}
+
+/* Gives meshes that never had colors the one passed in, anything else is left alone */
+func withColor(mesh Mesh, color Color) Mesh {
+	switch typed := mesh.(type) {
+	case *Mesh2NoRgba:
+		colored := Mesh2Rgba{Header: typed.Header, Verts: make([]VertexModern, len(typed.Verts)), Faces: typed.Faces}
+		colored.Header.VertexSize = VertexModernSize
+		for i := range typed.Verts {
+			colored.Verts[i] = typed.Verts[i].ModernColor(color)
+		}
+		return &colored
+	case *Model:
+		if typed.Present.Has(AttributeColors) {
+			return typed
+		}
+		colored := *typed
+		colored.Present |= AttributeColors
+		colored.Colors = make([]Color, len(typed.Positions))
+		for i := range colored.Colors {
+			colored.Colors[i] = color
+		}
+		return &colored
+	default:
+		return mesh
+	}
+}
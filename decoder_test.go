@@ -0,0 +1,132 @@
+package mesh_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"github.com/MojaveMF/mesh"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestEncoderDecoderCompression(t *testing.T) {
+	original := decodeFile(t, "./testdata/output.v4")
+	for _, compression := range []mesh.Compression{mesh.CompressionNone, mesh.CompressionGzip, mesh.CompressionZlib} {
+		buffer := bytes.Buffer{}
+		encoder := mesh.NewEncoder(&buffer, mesh.EncodeCompression(compression, 9))
+		if err := encoder.Encode(original); err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := mesh.NewDecoder(&buffer, mesh.DecodeCompression(compression)).Decode()
+		if err != nil {
+			t.Fatalf("compression %d: %s", compression, err)
+		}
+		if !reflect.DeepEqual(decoded, original) {
+			t.Errorf("compression %d changed the mesh", compression)
+		}
+	}
+}
+
+func TestEncoderSubVersion(t *testing.T) {
+	buffer := bytes.Buffer{}
+	encoder := mesh.NewEncoder(&buffer, mesh.EncodeVersion(mesh.MeshVersion3_01))
+	if err := encoder.Encode(decodeFile(t, "./testdata/output.v4")); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buffer.Bytes(), []byte("version 3.01\n")) {
+		t.Errorf("wrong version line %q", buffer.Bytes()[:13])
+	}
+
+	model, err := mesh.NewDecoder(&buffer).DecodeModel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.Version != mesh.MeshVersion3_01 {
+		t.Errorf("expected 3.01 got %d", model.Version)
+	}
+
+	if err := encoder.Encode(model); err != nil {
+		t.Fatal(err)
+	}
+	if err := mesh.NewEncoder(&buffer, mesh.EncodeVersion(mesh.MeshVersion1)).Encode(model); !errors.Is(err, mesh.ErrBadMeshVersion) {
+		t.Errorf("expected bad version got %v", err)
+	}
+}
+
+func TestDecoderOptions(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoder := mesh.NewDecoder(bytes.NewReader(data), mesh.DecodeVersions(mesh.MeshVersion2|mesh.MeshVersion3))
+	if _, err := decoder.Decode(); !errors.Is(err, mesh.ErrVersionNotAllowed) {
+		t.Errorf("expected version not allowed got %v", err)
+	}
+
+	decoder = mesh.NewDecoder(bytes.NewReader(data), mesh.DecodeLimits(mesh.Limits{MaxBones: 2}))
+	if _, err := decoder.Decode(); !errors.Is(err, mesh.ErrLimitExceeded) {
+		t.Errorf("expected limit error got %v", err)
+	}
+
+	decoder = mesh.NewDecoder(bytes.NewReader(data[:len(data)/2]), mesh.DecodeLenient(true))
+	if partial, err := decoder.Decode(); partial == nil || !errors.Is(err, mesh.ErrPartialMesh) {
+		t.Errorf("expected partial mesh got %v", err)
+	}
+}
+
+func TestDecoderColor(t *testing.T) {
+	red := mesh.Color{255, 0, 0, 255}
+	decoded, err := mesh.NewDecoder(bytes.NewReader(noRgbaMesh()), mesh.DecodeColor(red)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	colored, ok := decoded.(*mesh.Mesh2Rgba)
+	if !ok {
+		t.Fatalf("expected colored verts got %T", decoded)
+	}
+	if vertex := colored.Verts[0]; (mesh.Color{vertex.R, vertex.G, vertex.B, vertex.A}) != red {
+		t.Errorf("expected red got %+v", vertex)
+	}
+}
+
+func TestEncoderClosesOnError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	buffer := bytes.Buffer{}
+	encoder := mesh.NewEncoder(&buffer, mesh.EncodeCompression(mesh.CompressionGzip, 9))
+	if err := encoder.EncodeContext(ctx, decodeFile(t, "./testdata/output.v4")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled encode got %v", err)
+	}
+
+	/* A closed gzip stream ends with its footer even when nothing went in */
+	reader, err := gzip.NewReader(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Errorf("compressor was not closed: %s", err)
+	}
+}
+
+/* Meshes the package doesnt know still satisfy the interface */
+type customMesh struct {
+	*mesh.Mesh3
+}
+
+func TestEncoderUnknownMesh(t *testing.T) {
+	custom := customMesh{decodeFile(t, "./testdata/output.v3").(*mesh.Mesh3)}
+	for _, version := range []uint8{mesh.MeshVersion2, mesh.MeshVersion4} {
+		if err := mesh.NewEncoder(io.Discard, mesh.EncodeVersion(version)).Encode(custom); !errors.Is(err, mesh.ErrBadMeshVersion) {
+			t.Errorf("version %d: expected bad version got %v", version, err)
+		}
+	}
+	if mesh.EncodeMeshVersion(custom, mesh.MeshVersion4) != nil {
+		t.Errorf("expected no conversion for an unknown mesh")
+	}
+}
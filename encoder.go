@@ -0,0 +1,144 @@
+package mesh
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+)
+
+/* Holds the settings for every mesh written to one stream */
+type Encoder struct {
+	stream      io.Writer
+	version     uint8 /* 0 writes each mesh as its own version */
+	colors      ColorOptions
+	compression Compression
+	level       int
+	failOnLoss  bool
+	report      ColorReport /* from the last Encode */
+	loss        LossReport
+}
+
+type EncoderOption func(*Encoder)
+
+func NewEncoder(stream io.Writer, opts ...EncoderOption) *Encoder {
+	encoder := Encoder{stream: stream, level: gzip.DefaultCompression}
+	for _, opt := range opts {
+		opt(&encoder)
+	}
+	return &encoder
+}
+
+/* Any single MeshVersion, 3.01 and 4.01 get their own version line */
+func EncodeVersion(version uint8) EncoderOption {
+	return func(E *Encoder) {
+		E.version = version
+	}
+}
+
+/* Used when the mesh has no colors and the output stores them */
+func EncodeColor(color Color) EncoderOption {
+	return func(E *Encoder) {
+		E.colors.Fill = &color
+	}
+}
+
+/* See ApplyColors, replaces anything set by EncodeColor */
+func EncodeColors(opts ColorOptions) EncoderOption {
+	return func(E *Encoder) {
+		E.colors = opts
+	}
+}
+
+/* Encode returns a *LossError and writes nothing when the version cant hold the whole mesh */
+func EncodeFailOnLoss(fail bool) EncoderOption {
+	return func(E *Encoder) {
+		E.failOnLoss = fail
+	}
+}
+
+/* Level is the same as compress/flate, every Encode is its own compressed stream */
+func EncodeCompression(compression Compression, level int) EncoderOption {
+	return func(E *Encoder) {
+		E.compression = compression
+		E.level = level
+	}
+}
+
+/* Version a mesh is written as when nothing else is asked for */
+func ownVersion(mesh Mesh) uint8 {
+	switch typed := mesh.(type) {
+	case *Mesh2NoRgba, *Mesh2Rgba:
+		return MeshVersion2
+	case *Mesh3:
+		return MeshVersion3
+	case *Model:
+		if typed.Version != 0 {
+			return typed.Version
+		}
+	}
+	return MeshVersion4
+}
+
+func (E *Encoder) Encode(mesh Mesh) error {
+	return E.EncodeContext(context.Background(), mesh)
+}
+
+/* Stops with an *EncodeError once ctx is done, the stream is left with whatever was written */
+func (E *Encoder) EncodeContext(ctx context.Context, mesh Mesh) error {
+	version := E.version
+	if version == 0 {
+		version = ownVersion(mesh)
+	}
+	E.loss = ExportLoss(mesh, version)
+	mesh, E.report = ApplyColors(mesh, version, E.colors)
+	if versionFamily(version) == MeshVersion1 || versionFamily(version) == MeshVersion2 {
+		/* The color options decide what happens to colors here */
+		E.loss.set(LossColors, E.report.Dropped, false)
+	}
+	if E.failOnLoss && E.loss.Lossy() {
+		return &LossError{version, E.loss}
+	}
+
+	output := E.stream
+	var compressor io.WriteCloser
+	var err error
+	switch E.compression {
+	case CompressionGzip:
+		compressor, err = gzip.NewWriterLevel(E.stream, E.level)
+	case CompressionZlib:
+		compressor, err = zlib.NewWriterLevel(E.stream, E.level)
+	}
+	if err != nil {
+		return err
+	}
+	if compressor != nil {
+		output = compressor
+	}
+
+	err = writeVersion(mesh, version, contextStream(ctx, output, version))
+	if compressor != nil {
+		/* Closed either way so a failed write doesnt leak the compressor */
+		err = errors.Join(err, compressor.Close())
+	}
+	return err
+}
+
+/* What the last Encode did to colors, check Lossy to find out if any were lost */
+func (E *Encoder) ColorReport() ColorReport {
+	return E.report
+}
+
+/* What the last Encode lost, colors included */
+func (E *Encoder) LossReport() LossReport {
+	return E.loss
+}
+
+func writeVersion(mesh Mesh, version uint8, stream io.Writer) error {
+	codec, ok := codecForVersion(version)
+	if !ok || codec.Encode == nil {
+		return ErrBadMeshVersion
+	}
+	return codec.Encode(stream, mesh)
+}
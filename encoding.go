@@ -0,0 +1,212 @@
+package mesh
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+var ErrWrongMeshType = errors.New("mesh data is a different version than the type it was unmarshaled into")
+
+/* Counts what gets through so the plain Write methods can back WriteTo */
+type countingWriter struct {
+	stream  io.Writer
+	written int64
+}
+
+func (W *countingWriter) Write(data []byte) (int, error) {
+	written, err := W.stream.Write(data)
+	W.written += int64(written)
+	return written, err
+}
+
+func writeTo(stream io.Writer, write func(io.Writer) error) (int64, error) {
+	counter := countingWriter{stream: stream}
+	err := write(&counter)
+	return counter.written, err
+}
+
+func marshal(write func(io.Writer) error) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	if err := write(&buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+/* Decodes into a copy, nothing in the result points back at data */
+func unmarshal[T any](data []byte) (*T, error) {
+	decoded, err := DecodeMesh(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	typed, ok := any(decoded).(*T)
+	if !ok {
+		return nil, ErrWrongMeshType
+	}
+	return typed, nil
+}
+
+/* v1 isnt handled by DecodeMesh, the version line is checked here instead */
+func unmarshal1(data []byte) (*Mesh1, uint8, error) {
+	reader := bytes.NewReader(data)
+	version, err := MeshVersion(reader)
+	if err != nil {
+		return nil, 0, &DecodeError{0, SectionHeader, -1, 0, err}
+	}
+	if versionFamily(version) != MeshVersion1 {
+		return nil, version, ErrWrongMeshType
+	}
+	stream := MeshStream1{reader}
+	mesh1, err := stream.LoadMesh()
+	return mesh1, version, err
+}
+
+func (M *Mesh1) WriteTo(stream io.Writer) (int64, error) {
+	return writeTo(stream, M.Write)
+}
+
+func (M *Mesh1) MarshalBinary() ([]byte, error) {
+	return marshal(M.Write)
+}
+
+func (M *Mesh1) UnmarshalBinary(data []byte) error {
+	decoded, _, err := unmarshal1(data)
+	if err != nil {
+		return err
+	}
+	*M = *decoded
+	return nil
+}
+
+func (M *Mesh2NoRgba) WriteTo(stream io.Writer) (int64, error) {
+	return writeTo(stream, M.Write)
+}
+
+func (M *Mesh2NoRgba) MarshalBinary() ([]byte, error) {
+	return marshal(M.Write)
+}
+
+func (M *Mesh2NoRgba) UnmarshalBinary(data []byte) error {
+	decoded, err := unmarshal[Mesh2NoRgba](data)
+	if err != nil {
+		return err
+	}
+	*M = *decoded
+	return nil
+}
+
+func (M *Mesh2Rgba) WriteTo(stream io.Writer) (int64, error) {
+	return writeTo(stream, M.Write)
+}
+
+func (M *Mesh2Rgba) MarshalBinary() ([]byte, error) {
+	return marshal(M.Write)
+}
+
+func (M *Mesh2Rgba) UnmarshalBinary(data []byte) error {
+	decoded, err := unmarshal[Mesh2Rgba](data)
+	if err != nil {
+		return err
+	}
+	*M = *decoded
+	return nil
+}
+
+func (M *Mesh3) WriteTo(stream io.Writer) (int64, error) {
+	return writeTo(stream, M.Write)
+}
+
+func (M *Mesh3) MarshalBinary() ([]byte, error) {
+	return marshal(M.Write)
+}
+
+/* 3.01 data is taken too, it comes back out as 3.00 */
+func (M *Mesh3) UnmarshalBinary(data []byte) error {
+	decoded, err := unmarshal[Mesh3](data)
+	if err != nil {
+		return err
+	}
+	*M = *decoded
+	return nil
+}
+
+func (M *Mesh4) WriteTo(stream io.Writer) (int64, error) {
+	return writeTo(stream, M.Write)
+}
+
+func (M *Mesh4) MarshalBinary() ([]byte, error) {
+	return marshal(M.Write)
+}
+
+/* 4.01 data is taken too, it comes back out as 4.00 */
+func (M *Mesh4) UnmarshalBinary(data []byte) error {
+	decoded, err := unmarshal[Mesh4](data)
+	if err != nil {
+		return err
+	}
+	*M = *decoded
+	return nil
+}
+
+func (M *Model) WriteTo(stream io.Writer) (int64, error) {
+	return writeTo(stream, M.Write)
+}
+
+func (M *Model) MarshalBinary() ([]byte, error) {
+	return marshal(M.Write)
+}
+
+/* Any version is taken and kept, v1 included since Write can produce it */
+func (M *Model) UnmarshalBinary(data []byte) error {
+	mesh1, version, err := unmarshal1(data)
+	switch {
+	case err == nil:
+		*M = *mesh1.ToModel()
+		M.Version = version
+		return nil
+	case err != ErrWrongMeshType:
+		return err
+	}
+
+	decoded, err := DecodeModel(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	*M = *decoded
+	return nil
+}
+
+/*
+Buffers whatever is left in reader so LoadMesh works from memory, up to
+DefaultLimits.MaxBytes so an untrusted reader cant fill up memory
+*/
+func readAll(stream *io.Reader, reader io.Reader) (int64, error) {
+	limit := DefaultLimits.MaxBytes
+	if limit > 0 {
+		reader = io.LimitReader(reader, limit+1)
+	}
+	buffer := bytes.Buffer{}
+	read, err := buffer.ReadFrom(reader)
+	if limit > 0 && read > limit {
+		return read, &LimitError{"bytes", uint64(read), uint64(limit)}
+	}
+	*stream = bytes.NewReader(buffer.Bytes())
+	return read, err
+}
+
+func (S *MeshStream1) ReadFrom(reader io.Reader) (int64, error) {
+	return readAll(&S.Stream, reader)
+}
+
+func (S *MeshStream2) ReadFrom(reader io.Reader) (int64, error) {
+	return readAll(&S.Stream, reader)
+}
+
+func (S *MeshStream3) ReadFrom(reader io.Reader) (int64, error) {
+	return readAll(&S.Stream, reader)
+}
+
+func (S *MeshStream4) ReadFrom(reader io.Reader) (int64, error) {
+	return readAll(&S.Stream, reader)
+}
@@ -0,0 +1,159 @@
+package mesh_test
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"github.com/MojaveMF/mesh"
+	"io"
+	"os"
+	"testing"
+)
+
+type binaryMesh interface {
+	io.WriterTo
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+func TestBinaryMarshal(t *testing.T) {
+	model := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4).ToModel()
+
+	for name, pair := range map[string][2]binaryMesh{
+		"norgba": {mustDecode(t, noRgbaMesh()).(*mesh.Mesh2NoRgba), &mesh.Mesh2NoRgba{}},
+		"v2":     {decodeFile(t, "./testdata/output.v2").(*mesh.Mesh2Rgba), &mesh.Mesh2Rgba{}},
+		"v3":     {decodeFile(t, "./testdata/output.v3").(*mesh.Mesh3), &mesh.Mesh3{}},
+		"v4":     {decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4), &mesh.Mesh4{}},
+		"model":  {model, &mesh.Model{}},
+	} {
+		original, empty := pair[0], pair[1]
+		data, err := original.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		output := bytes.Buffer{}
+		written, err := original.WriteTo(&output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if written != int64(len(data)) || !bytes.Equal(output.Bytes(), data) {
+			t.Errorf("%s: WriteTo wrote %d bytes, MarshalBinary %d", name, written, len(data))
+		}
+
+		if err := empty.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		again, err := empty.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(again, data) {
+			t.Errorf("%s: unmarshaled mesh writes different bytes", name)
+		}
+	}
+}
+
+/* v1 is written with V flipped and read back as is, so only positions survive a round trip */
+func TestBinaryMarshalV1(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := mesh.MeshStream1{bytes.NewReader(data[13:])}
+	mesh1, err := stream.LoadMesh()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	marshaled, err := mesh1.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := mesh.Model{}
+	if err := model.UnmarshalBinary(marshaled); err != nil || model.Version != mesh.MeshVersion1_01 {
+		t.Errorf("expected a 1.01 model got version %d: %v", model.Version, err)
+	}
+	unmarshaled := mesh.Mesh1{}
+	if err := unmarshaled.UnmarshalBinary(marshaled); err != nil {
+		t.Fatal(err)
+	}
+	if unmarshaled.FaceCount != mesh1.FaceCount || len(unmarshaled.Verts) != len(mesh1.Verts) {
+		t.Fatalf("expected %d faces got %d", mesh1.FaceCount, unmarshaled.FaceCount)
+	}
+	for i, vertex := range unmarshaled.Verts {
+		if !closeTo(vertex.Px, mesh1.Verts[i].Px) || !closeTo(vertex.Py, mesh1.Verts[i].Py) || !closeTo(vertex.Pz, mesh1.Verts[i].Pz) {
+			t.Fatalf("vertex %d moved", i)
+		}
+	}
+}
+
+func mustDecode(t *testing.T, data []byte) mesh.Mesh {
+	decoded, err := mesh.DecodeMesh(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestUnmarshalWrongType(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&mesh.Mesh3{}).UnmarshalBinary(data); !errors.Is(err, mesh.ErrWrongMeshType) {
+		t.Errorf("expected v4 data to be refused by Mesh3 got %v", err)
+	}
+	if err := (&mesh.Mesh1{}).UnmarshalBinary(data); !errors.Is(err, mesh.ErrWrongMeshType) {
+		t.Errorf("expected v4 data to be refused by Mesh1 got %v", err)
+	}
+	if err := (&mesh.Mesh2Rgba{}).UnmarshalBinary(noRgbaMesh()); !errors.Is(err, mesh.ErrWrongMeshType) {
+		t.Errorf("expected a 36 byte v2 to be refused by Mesh2Rgba got %v", err)
+	}
+}
+
+func TestStreamReadFrom(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := mesh.MeshStream4{}
+	read, err := stream.ReadFrom(plainReader{bytes.NewReader(data[13:])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != int64(len(data)-13) {
+		t.Errorf("expected %d bytes read got %d", len(data)-13, read)
+	}
+	mesh4, err := stream.LoadMesh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encode(t, mesh4), data) {
+		t.Errorf("buffered stream decodes differently")
+	}
+
+	/* bytes.Buffer hands itself over through the stream */
+	buffer := bytes.Buffer{}
+	if _, err := buffer.ReadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mesh4.WriteTo(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffer.Bytes()[len(data):], data) {
+		t.Errorf("WriteTo after ReadFrom wrote something else")
+	}
+}
+
+func TestStreamReadFromLimit(t *testing.T) {
+	limits := mesh.DefaultLimits
+	t.Cleanup(func() { mesh.DefaultLimits = limits })
+	mesh.DefaultLimits.MaxBytes = 1000
+
+	stream := mesh.MeshStream4{}
+	_, err := stream.ReadFrom(plainReader{bytes.NewReader(make([]byte, 5000))})
+	limitErr := &mesh.LimitError{}
+	if !errors.As(err, &limitErr) || limitErr.Field != "bytes" || stream.Stream != nil {
+		t.Errorf("expected bytes limit error got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package mesh_test
+
+import (
+	"bytes"
+	"github.com/MojaveMF/mesh"
+	"os"
+	"reflect"
+	"testing"
+)
+
+/* Bytes reader lets the decoders check sizes against the input before allocating */
+var fuzzOptions = mesh.DecodeOptions{Limits: mesh.DefaultLimits}
+
+func addSeeds(f *testing.F, skipVersionLine bool) {
+	for _, path := range []string{"./testdata/output.v1", "./testdata/output.v2", "./testdata/output.v3", "./testdata/output.v4"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		if skipVersionLine {
+			data = data[13:]
+		}
+		f.Add(data)
+		f.Add(data[:len(data)/2])
+	}
+
+	/* Small meshes give the fuzzer something it can mutate quickly */
+	small := twoTriangles()
+	for _, meshData := range []mesh.Mesh{small.ExportV2(), small, small.ExportV4()} {
+		buffer := bytes.Buffer{}
+		if err := meshData.Write(&buffer); err != nil {
+			f.Fatal(err)
+		}
+		data := buffer.Bytes()
+		if skipVersionLine {
+			data = data[13:]
+		}
+		f.Add(data)
+	}
+	f.Add([]byte{})
+	f.Add([]byte("version 4.00\n"))
+}
+
+func FuzzDecodeMesh(f *testing.F) {
+	addSeeds(f, false)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		mesh.DecodeMeshOptions(bytes.NewReader(data), fuzzOptions)
+		lenient := fuzzOptions
+		lenient.Lenient = true
+		mesh.DecodeMeshOptions(bytes.NewReader(data), lenient)
+
+		/* Aliasing must not change what comes out */
+		aligned := mesh.AlignedBuffer(len(data))
+		copy(aligned, data)
+		expected, expectedErr := mesh.DecodeMeshOptions(bytes.NewReader(data), lenient)
+		decoded, err := mesh.DecodeBytesOptions(aligned, lenient)
+		if (err == nil) != (expectedErr == nil) || (err != nil && err.Error() != expectedErr.Error()) {
+			t.Fatalf("got %v expected %v", err, expectedErr)
+		}
+		if !reflect.DeepEqual(decoded, expected) && !bytes.Equal(encode(t, decoded), encode(t, expected)) {
+			t.Fatalf("aliased decode differs")
+		}
+	})
+}
+
+/* NaNs never DeepEqual, so decodes are compared by their encoding */
+func encode(t *testing.T, decoded mesh.Mesh) []byte {
+	if decoded == nil {
+		return nil
+	}
+	buffer := bytes.Buffer{}
+	if err := decoded.Write(&buffer); err != nil {
+		t.Fatalf("decoded mesh did not encode: %s", err)
+	}
+	return buffer.Bytes()
+}
+
+func FuzzLoadMesh1(f *testing.F) {
+	addSeeds(f, true)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		stream := mesh.MeshStream1{bytes.NewReader(data)}
+		stream.LoadMeshOptions(fuzzOptions)
+	})
+}
+
+func FuzzLoadMesh2(f *testing.F) {
+	addSeeds(f, true)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		stream := mesh.MeshStream2{bytes.NewReader(data)}
+		stream.LoadMeshOptions(fuzzOptions)
+	})
+}
+
+func FuzzLoadMesh3(f *testing.F) {
+	addSeeds(f, true)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		stream := mesh.MeshStream3{bytes.NewReader(data)}
+		stream.LoadMeshOptions(fuzzOptions)
+	})
+}
+
+func FuzzLoadMesh4(f *testing.F) {
+	addSeeds(f, true)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		stream := mesh.MeshStream4{bytes.NewReader(data)}
+		stream.LoadMeshOptions(fuzzOptions)
+	})
+}
+
+func FuzzReadNumber(f *testing.F) {
+	f.Add([]byte("1.424308,"))
+	f.Add([]byte("-0.5]"))
+	f.Add([]byte("[1]"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		stream := mesh.MeshStream1{bytes.NewReader(data)}
+		stream.ReadNumber()
+	})
+}
+
+func FuzzReadVector3(f *testing.F) {
+	f.Add([]byte("[-1.424308,-3.167460,1.794417]"))
+	f.Add([]byte("[0,0,0]"))
+	f.Add([]byte("[1,2"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		stream := mesh.MeshStream1{bytes.NewReader(data)}
+		stream.ReadVector3()
+	})
+}
+
+/*
+Anything that decodes has to write back out and decode to the same thing.
+Comparing the encodings keeps NaNs from failing the check.
+*/
+func FuzzRoundTrip(f *testing.F) {
+	addSeeds(f, false)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decoded, err := mesh.DecodeMeshOptions(bytes.NewReader(data), fuzzOptions)
+		if err != nil {
+			return
+		}
+
+		first := bytes.Buffer{}
+		if err := decoded.Write(&first); err != nil {
+			t.Fatalf("decoded mesh did not encode: %s", err)
+		}
+		redecoded, err := mesh.DecodeMeshOptions(bytes.NewReader(first.Bytes()), fuzzOptions)
+		if err != nil {
+			t.Fatalf("encoded mesh did not decode: %s", err)
+		}
+		second := bytes.Buffer{}
+		if err := redecoded.Write(&second); err != nil {
+			t.Fatalf("decoded mesh did not encode: %s", err)
+		}
+		if !bytes.Equal(first.Bytes(), second.Bytes()) {
+			t.Fatalf("round trip changed the mesh")
+		}
+	})
+}
+
+/* Streaming has to write what decoding, exporting and writing would */
+func FuzzTranscode(f *testing.F) {
+	addSeeds(f, false)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, target := range []uint8{mesh.MeshVersion2, mesh.MeshVersion3, mesh.MeshVersion4} {
+			decoded, err := mesh.DecodeMeshOptions(bytes.NewReader(data), fuzzOptions)
+			output := bytes.Buffer{}
+			transcodeErr := mesh.TranscodeOptions(bytes.NewReader(data), &output, target, fuzzOptions)
+			if (err == nil) != (transcodeErr == nil) {
+				t.Fatalf("decode gave %v transcode gave %v", err, transcodeErr)
+			}
+			if err != nil {
+				continue
+			}
+			if !bytes.Equal(output.Bytes(), encode(t, mesh.EncodeMeshVersion(decoded, target))) {
+				t.Fatalf("transcode to %d differs from decode and export", target)
+			}
+		}
+	})
+}
@@ -1,3 +1,3 @@
 module github.com/MojaveMF/mesh
 
-go 1.21.5
+go 1.23
@@ -0,0 +1,267 @@
+package mesh
+
+import (
+	"iter"
+	"slices"
+)
+
+/*
+Iterators walk the mesh in place without building anything, faces that
+point past the verts are skipped. Keys are the face or vertex index in the
+whole mesh so they line up with the slices on the mesh.
+*/
+
+func sliceTriangles[T any](verts []T, faces []Face) iter.Seq2[int, [3]T] {
+	return func(yield func(int, [3]T) bool) {
+		for i, face := range faces {
+			if !faceInRange(face, len(verts)) {
+				continue
+			}
+			if !yield(i, [3]T{verts[face.A], verts[face.B], verts[face.C]}) {
+				return
+			}
+		}
+	}
+}
+
+func faceSpan(faces []Face, start uint32, end uint32) iter.Seq2[int, Face] {
+	return func(yield func(int, Face) bool) {
+		for i := int(start); i < int(end); i++ {
+			if !yield(i, faces[i]) {
+				return
+			}
+		}
+	}
+}
+
+/* Same clamping as lodRanges without building every level */
+func lodSpan(faces []Face, lods []uint32, lod int) iter.Seq2[int, Face] {
+	total := uint32(len(faces))
+	switch {
+	case len(lods) < 2 && lod == 0:
+		return faceSpan(faces, 0, total)
+	case lod < 0 || lod+1 >= len(lods):
+		return faceSpan(faces, 0, 0)
+	}
+	start, end := min(lods[lod], total), min(lods[lod+1], total)
+	return faceSpan(faces, start, max(start, end))
+}
+
+/* Meshes without subsets are a single subset holding every face */
+func subsetSpan(faces []Face, count int, subset int, span func(int) (uint32, uint32)) iter.Seq2[int, Face] {
+	total := uint32(len(faces))
+	switch {
+	case count == 0 && subset == 0:
+		return faceSpan(faces, 0, total)
+	case subset < 0 || subset >= count:
+		return faceSpan(faces, 0, 0)
+	}
+	begin, length := span(subset)
+	start := min(begin, total)
+	return faceSpan(faces, start, uint32(min(uint64(begin)+uint64(length), uint64(total))))
+}
+
+func noInfluences(yield func(uint16, uint8) bool) {}
+
+func (M *Mesh1) Vertices() iter.Seq2[int, VertexV1] {
+	return slices.All(M.Verts)
+}
+
+/* Every 3 verts are a triangle, a trailing 1 or 2 are left out */
+func (M *Mesh1) Triangles() iter.Seq2[int, [3]VertexV1] {
+	return func(yield func(int, [3]VertexV1) bool) {
+		for i := 0; i+2 < len(M.Verts); i += 3 {
+			if !yield(i/3, [3]VertexV1{M.Verts[i], M.Verts[i+1], M.Verts[i+2]}) {
+				return
+			}
+		}
+	}
+}
+
+/* v1 has a single level and its faces are implied by the vertex order */
+func (M *Mesh1) LodFaces(lod int) iter.Seq2[int, Face] {
+	return func(yield func(int, Face) bool) {
+		if lod != 0 {
+			return
+		}
+		for i := 0; i+2 < len(M.Verts); i += 3 {
+			index := uint32(i)
+			if !yield(i/3, Face{index, index + 1, index + 2}) {
+				return
+			}
+		}
+	}
+}
+
+func (M *Mesh1) SubsetFaces(subset int) iter.Seq2[int, Face] {
+	return M.LodFaces(subset)
+}
+
+func (M *Mesh1) Influences(vertex int) iter.Seq2[uint16, uint8] {
+	return noInfluences
+}
+
+func (M *Mesh2NoRgba) Vertices() iter.Seq2[int, VertexNoRgba] {
+	return slices.All(M.Verts)
+}
+
+func (M *Mesh2NoRgba) Triangles() iter.Seq2[int, [3]VertexNoRgba] {
+	return sliceTriangles(M.Verts, M.Faces)
+}
+
+func (M *Mesh2NoRgba) LodFaces(lod int) iter.Seq2[int, Face] {
+	return lodSpan(M.Faces, nil, lod)
+}
+
+func (M *Mesh2NoRgba) SubsetFaces(subset int) iter.Seq2[int, Face] {
+	return subsetSpan(M.Faces, 0, subset, nil)
+}
+
+func (M *Mesh2NoRgba) Influences(vertex int) iter.Seq2[uint16, uint8] {
+	return noInfluences
+}
+
+func (M *Mesh2Rgba) Vertices() iter.Seq2[int, VertexModern] {
+	return slices.All(M.Verts)
+}
+
+func (M *Mesh2Rgba) Triangles() iter.Seq2[int, [3]VertexModern] {
+	return sliceTriangles(M.Verts, M.Faces)
+}
+
+func (M *Mesh2Rgba) LodFaces(lod int) iter.Seq2[int, Face] {
+	return lodSpan(M.Faces, nil, lod)
+}
+
+func (M *Mesh2Rgba) SubsetFaces(subset int) iter.Seq2[int, Face] {
+	return subsetSpan(M.Faces, 0, subset, nil)
+}
+
+func (M *Mesh2Rgba) Influences(vertex int) iter.Seq2[uint16, uint8] {
+	return noInfluences
+}
+
+func (M *Mesh3) Vertices() iter.Seq2[int, VertexModern] {
+	return slices.All(M.Verts)
+}
+
+func (M *Mesh3) Triangles() iter.Seq2[int, [3]VertexModern] {
+	return sliceTriangles(M.Verts, M.Faces)
+}
+
+func (M *Mesh3) LodFaces(lod int) iter.Seq2[int, Face] {
+	return lodSpan(M.Faces, M.Lods, lod)
+}
+
+func (M *Mesh3) SubsetFaces(subset int) iter.Seq2[int, Face] {
+	return subsetSpan(M.Faces, 0, subset, nil)
+}
+
+func (M *Mesh3) Influences(vertex int) iter.Seq2[uint16, uint8] {
+	return noInfluences
+}
+
+func (M *Mesh4) Vertices() iter.Seq2[int, VertexModern] {
+	return slices.All(M.Verts)
+}
+
+func (M *Mesh4) Triangles() iter.Seq2[int, [3]VertexModern] {
+	return sliceTriangles(M.Verts, M.Faces)
+}
+
+func (M *Mesh4) LodFaces(lod int) iter.Seq2[int, Face] {
+	return lodSpan(M.Faces, M.Lods, lod)
+}
+
+func (M *Mesh4) SubsetFaces(subset int) iter.Seq2[int, Face] {
+	return subsetSpan(M.Faces, len(M.MeshSubsets), subset, func(i int) (uint32, uint32) {
+		return M.MeshSubsets[i].FacesBegin, M.MeshSubsets[i].FacesLength
+	})
+}
+
+/* First subset holding the vertex, -1 when none do */
+func (M *Mesh4) vertexSubset(vertex int) int {
+	for i, subset := range M.MeshSubsets {
+		if uint64(vertex) >= uint64(subset.VertsBegin) && uint64(vertex) < uint64(subset.VertsBegin)+uint64(subset.VertsLength) {
+			return i
+		}
+	}
+	return -1
+}
+
+/* Skeleton bone and weight for each slot in use, envelopes are mapped out of their subset like ToModel */
+func (M *Mesh4) Influences(vertex int) iter.Seq2[uint16, uint8] {
+	return func(yield func(uint16, uint8) bool) {
+		if len(M.Bones) == 0 || vertex < 0 || vertex >= min(len(M.Envelopes), len(M.Verts)) {
+			return
+		}
+		envelope := M.Envelopes[vertex]
+		owner := M.vertexSubset(vertex)
+		for slot, local := range envelope.Bones {
+			bone := uint16(local)
+			if owner >= 0 {
+				subset := &M.MeshSubsets[owner]
+				if local >= byte(min(subset.NumBonesIndicies, MaxSubsetBones)) {
+					continue
+				}
+				bone = subset.BoneIndicies[local]
+			}
+			if envelope.Weights[slot] == 0 || bone == NoBone {
+				continue
+			}
+			if !yield(bone, envelope.Weights[slot]) {
+				return
+			}
+		}
+	}
+}
+
+func (M *Model) Vertices() iter.Seq2[int, VertexModern] {
+	return func(yield func(int, VertexModern) bool) {
+		for i := range M.Positions {
+			if !yield(i, M.vertex(i)) {
+				return
+			}
+		}
+	}
+}
+
+func (M *Model) Triangles() iter.Seq2[int, [3]VertexModern] {
+	return func(yield func(int, [3]VertexModern) bool) {
+		for i, face := range M.Faces {
+			if !faceInRange(face, len(M.Positions)) {
+				continue
+			}
+			if !yield(i, [3]VertexModern{M.vertex(int(face.A)), M.vertex(int(face.B)), M.vertex(int(face.C))}) {
+				return
+			}
+		}
+	}
+}
+
+func (M *Model) LodFaces(lod int) iter.Seq2[int, Face] {
+	return lodSpan(M.Faces, M.Lods, lod)
+}
+
+func (M *Model) SubsetFaces(subset int) iter.Seq2[int, Face] {
+	return subsetSpan(M.Faces, len(M.Subsets), subset, func(i int) (uint32, uint32) {
+		return M.Subsets[i].FacesBegin, M.Subsets[i].FacesLength
+	})
+}
+
+func (M *Model) Influences(vertex int) iter.Seq2[uint16, uint8] {
+	return func(yield func(uint16, uint8) bool) {
+		if vertex < 0 || vertex >= len(M.Skin) {
+			return
+		}
+		skin := M.Skin[vertex]
+		for slot, bone := range skin.Bones {
+			if skin.Weights[slot] == 0 || bone == NoBone {
+				continue
+			}
+			if !yield(bone, skin.Weights[slot]) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package mesh_test
+
+import (
+	"github.com/MojaveMF/mesh"
+	"testing"
+)
+
+func TestTriangles(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+	expected := mesh4.GetAllVerticies(mesh4.Faces)
+
+	count := 0
+	for i, triangle := range mesh4.Triangles() {
+		if i != count || triangle != [3]mesh.VertexModern(expected[i*3:i*3+3]) {
+			t.Fatalf("triangle %d differs", i)
+		}
+		count++
+	}
+	if count != len(mesh4.Faces) {
+		t.Errorf("expected %d triangles got %d", len(mesh4.Faces), count)
+	}
+
+	model := mesh4.ToModel()
+	for i, triangle := range model.Triangles() {
+		if triangle != [3]mesh.VertexModern(expected[i*3:i*3+3]) {
+			t.Fatalf("model triangle %d differs", i)
+		}
+	}
+
+	/* Stopping early has to work, range panics otherwise */
+	for range mesh4.Triangles() {
+		break
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		for _, triangle := range mesh4.Triangles() {
+			_ = triangle
+		}
+		for _, face := range mesh4.LodFaces(0) {
+			_ = face
+		}
+		for bone, weight := range mesh4.Influences(0) {
+			_, _ = bone, weight
+		}
+	})
+	if allocs > 3 {
+		t.Errorf("expected only the iterators themselves to allocate got %v", allocs)
+	}
+}
+
+func TestLodAndSubsetFaces(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+
+	for lod, span := range mesh4.ToModel().LodRanges() {
+		next := span[0]
+		for i, face := range mesh4.LodFaces(lod) {
+			if uint32(i) != next || face != mesh4.Faces[i] {
+				t.Fatalf("lod %d face %d out of place", lod, i)
+			}
+			next++
+		}
+		if next != span[1] {
+			t.Errorf("lod %d ended at %d expected %d", lod, next, span[1])
+		}
+	}
+	for range mesh4.LodFaces(len(mesh4.Lods)) {
+		t.Fatalf("expected nothing past the last lod")
+	}
+
+	for index, subset := range mesh4.MeshSubsets {
+		count := uint32(0)
+		for i := range mesh4.SubsetFaces(index) {
+			if uint32(i) != subset.FacesBegin+count {
+				t.Fatalf("subset %d face %d out of place", index, i)
+			}
+			count++
+		}
+		if count != subset.FacesLength {
+			t.Errorf("subset %d has %d faces expected %d", index, count, subset.FacesLength)
+		}
+	}
+
+	mesh2 := twoTriangles().ExportV2().(*mesh.Mesh2Rgba)
+	count := 0
+	for range mesh2.SubsetFaces(0) {
+		count++
+	}
+	if count != len(mesh2.Faces) {
+		t.Errorf("expected a mesh without subsets to be one subset")
+	}
+
+	mesh1 := twoTriangles().ExportV1()
+	for i, face := range mesh1.LodFaces(0) {
+		if face != (mesh.Face{uint32(i * 3), uint32(i*3 + 1), uint32(i*3 + 2)}) {
+			t.Errorf("v1 face %d is %v", i, face)
+		}
+	}
+}
+
+func TestInfluences(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+	model := mesh4.ToModel()
+
+	for vertex := range mesh4.Verts {
+		expected := map[uint16]uint8{}
+		for bone, weight := range model.Influences(vertex) {
+			expected[bone] += weight
+		}
+		for bone, weight := range mesh4.Influences(vertex) {
+			expected[bone] -= weight
+		}
+		for bone, weight := range expected {
+			if weight != 0 {
+				t.Fatalf("vertex %d bone %d weighs differently", vertex, bone)
+			}
+		}
+	}
+
+	for range twoTriangles().Influences(0) {
+		t.Errorf("expected no influences on v3")
+	}
+}
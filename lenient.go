@@ -0,0 +1,288 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+var ErrPartialMesh = errors.New("mesh was only partly recovered")
+
+/*
+Returned next to the mesh by lenient decodes that hit the end of the input.
+Err is the DecodeError saying where it stopped, the rest is what got dropped.
+Works with errors.Is(err, ErrPartialMesh).
+*/
+type PartialError struct {
+	Err error
+
+	VertsLost     int
+	EnvelopesLost int
+	FacesLost     int
+	DanglingFaces int /* complete faces that pointed at verts that were lost */
+	LodsLost      int
+	BonesLost     int
+	NameBytesLost int
+	SubsetsLost   int
+}
+
+/* Only says what was actually lost */
+func (E *PartialError) Error() string {
+	faces := "faces"
+	if E.DanglingFaces != 0 {
+		faces = fmt.Sprintf("faces (%d dangling)", E.DanglingFaces)
+	}
+	lost := []string{}
+	for _, count := range []struct {
+		name   string
+		amount int
+	}{
+		{"verts", E.VertsLost},
+		{"envelopes", E.EnvelopesLost},
+		{faces, E.FacesLost + E.DanglingFaces},
+		{"lods", E.LodsLost},
+		{"bones", E.BonesLost},
+		{"name bytes", E.NameBytesLost},
+		{"subsets", E.SubsetsLost},
+	} {
+		if count.amount != 0 {
+			lost = append(lost, fmt.Sprintf("%d %s", count.amount, count.name))
+		}
+	}
+	if len(lost) == 0 {
+		lost = append(lost, "nothing")
+	}
+	return fmt.Sprintf("partial mesh, lost %s: %s", strings.Join(lost, " "), E.Err)
+}
+
+func (E *PartialError) Unwrap() []error {
+	return []error{ErrPartialMesh, E.Err}
+}
+
+/* Lenient decodes size slices by what is left in the input, not what the header claims */
+func (R *meshReader) capped(count uint64, size uint8) uint64 {
+	if !R.opts.Lenient || R.remaining < 0 {
+		return count
+	}
+	return min(count, uint64(max(R.remaining-R.offset, 0))/uint64(size))
+}
+
+func truncation(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+/*
+Reads a section of declared items into a new slice, or one that aliases the
+input when it is all in memory. In lenient mode running out of input keeps
+the complete items, and every later section is skipped.
+*/
+func readSection[T any](R *meshReader, section Section, items *[]T, declared uint64) error {
+	if R.truncated != nil {
+		*items = make([]T, 0)
+		return nil
+	}
+	if err := R.cancelled(); err != nil {
+		return R.fail(section, -1, R.position(), err)
+	}
+	if aliased, ok := aliasSection[T](R, declared); ok {
+		*items = aliased
+		return nil
+	}
+
+	var read int
+	var err error
+	if R.remaining < 0 {
+		*items, err = readGrowing[T](R, section, declared)
+		read = len(*items)
+	} else {
+		*items = make([]T, R.capped(declared, uint8(elementSize[T]())))
+		read, err = readElements(R, section, *items, 0)
+	}
+	if err == nil && uint64(read) < declared {
+		err = R.fail(section, read, R.position(), io.ErrUnexpectedEOF)
+	}
+	if err == nil {
+		return nil
+	}
+	if !R.opts.Lenient || !truncation(err) {
+		return err
+	}
+
+	*items = (*items)[:read]
+	R.truncated = err
+	return nil
+}
+
+/*
+When the input cant say how much is left the header is not trusted with the
+allocation, the slice grows a chunk at a time as the data actually arrives
+*/
+func readGrowing[T any](R *meshReader, section Section, declared uint64) ([]T, error) {
+	chunk := uint64(max(contextChunk/elementSize[T](), 1))
+	items := make([]T, 0, min(declared, chunk))
+	for uint64(len(items)) < declared {
+		count := int(min(declared-uint64(len(items)), chunk))
+		items = slices.Grow(items, count)
+		read, err := readElements(R, section, items[len(items):len(items)+count], len(items))
+		items = items[:len(items)+read]
+		if err != nil {
+			return items, err
+		}
+	}
+	return items, nil
+}
+
+func (R *meshReader) partial() *PartialError {
+	if R.truncated == nil {
+		return nil
+	}
+	return &PartialError{Err: R.truncated}
+}
+
+func lost(declared uint64, kept int) int {
+	return int(declared - min(declared, uint64(kept)))
+}
+
+/*
+Drops faces that point at verts that never arrived, lods follow along. The
+prefix comes back for anything else that indexes faces, nil when none went.
+*/
+func dropDangling(faces []Face, lods []uint32, numVerts int, partial *PartialError) ([]Face, []uint32, []uint32) {
+	keep := make([]bool, len(faces))
+	for i, face := range faces {
+		keep[i] = faceInRange(face, numVerts)
+		if !keep[i] {
+			partial.DanglingFaces++
+		}
+	}
+	if partial.DanglingFaces == 0 {
+		return faces, lods, nil
+	}
+	prefix := prefixCount(keep)
+	return keepItems(faces, keep), remapLods(lods, prefix), prefix
+}
+
+/* Subsets follow dropped faces and get clamped to whatever arrived */
+func salvageSubsets(subsets []MeshSubset, facePrefix []uint32, numVerts, numFaces int) []MeshSubset {
+	kept := make([]MeshSubset, 0, len(subsets))
+	for _, subset := range subsets {
+		if facePrefix != nil {
+			subset.FacesBegin, subset.FacesLength = remapRange(facePrefix, subset.FacesBegin, subset.FacesLength)
+		}
+		subset.FacesBegin, subset.FacesLength = clampRange(subset.FacesBegin, subset.FacesLength, numFaces)
+		subset.VertsBegin, subset.VertsLength = clampRange(subset.VertsBegin, subset.VertsLength, numVerts)
+		subset.NumBonesIndicies = min(subset.NumBonesIndicies, MaxSubsetBones)
+		if subset.VertsLength == 0 && subset.FacesLength == 0 {
+			continue
+		}
+		kept = append(kept, subset)
+	}
+	return kept
+}
+
+func clampRange(begin, length uint32, total int) (uint32, uint32) {
+	begin = min(begin, uint32(total))
+	end := min(uint64(begin)+uint64(length), uint64(total))
+	return begin, uint32(end) - begin
+}
+
+/* Keeps lods up to the first one that goes backwards or past the faces */
+func validLods(lods []uint32, numFaces int) []uint32 {
+	for i, lod := range lods {
+		if lod > uint32(numFaces) || (i > 0 && lod < lods[i-1]) {
+			return lods[:i]
+		}
+	}
+	return lods
+}
+
+func (R *meshReader) salvage1(mesh *Mesh1) error {
+	partial := R.partial()
+	if partial == nil {
+		return nil
+	}
+	whole := len(mesh.Verts) / 3 * 3
+	partial.VertsLost = lost(uint64(mesh.FaceCount)*3, whole)
+	partial.FacesLost = lost(uint64(mesh.FaceCount), whole/3)
+	mesh.Verts = mesh.Verts[:whole]
+	mesh.FaceCount = uint32(whole / 3)
+	return partial
+}
+
+func (R *meshReader) salvage2(header *MeshHeader2, verts int, faces *[]Face) error {
+	partial := R.partial()
+	if partial == nil {
+		return nil
+	}
+	partial.VertsLost = lost(uint64(header.NumVerts), verts)
+	partial.FacesLost = lost(uint64(header.NumFaces), len(*faces))
+	*faces, _, _ = dropDangling(*faces, nil, verts, partial)
+
+	header.NumVerts = uint32(verts)
+	header.NumFaces = uint32(len(*faces))
+	return partial
+}
+
+func (R *meshReader) salvage3(mesh *Mesh3) error {
+	partial := R.partial()
+	if partial == nil {
+		return nil
+	}
+	header := &mesh.Header
+	partial.VertsLost = lost(uint64(header.NumVerts), len(mesh.Verts))
+	partial.FacesLost = lost(uint64(header.NumFaces), len(mesh.Faces))
+	mesh.Faces, mesh.Lods, _ = dropDangling(mesh.Faces, mesh.Lods, len(mesh.Verts), partial)
+	mesh.Lods = validLods(mesh.Lods, len(mesh.Faces))
+	partial.LodsLost = lost(uint64(header.NumLods), len(mesh.Lods))
+
+	header.NumVerts = uint32(len(mesh.Verts))
+	header.NumFaces = uint32(len(mesh.Faces))
+	header.NumLods = uint16(len(mesh.Lods))
+	return partial
+}
+
+func (R *meshReader) salvage4(mesh *Mesh4) error {
+	partial := R.partial()
+	if partial == nil {
+		return nil
+	}
+	header := &mesh.Header
+	partial.VertsLost = lost(uint64(header.NumVerts), len(mesh.Verts))
+	if header.NumBones > 0 {
+		partial.EnvelopesLost = lost(uint64(header.NumVerts), len(mesh.Envelopes))
+	}
+	partial.FacesLost = lost(uint64(header.NumFaces), len(mesh.Faces))
+
+	var facePrefix []uint32
+	mesh.Faces, mesh.Lods, facePrefix = dropDangling(mesh.Faces, mesh.Lods, len(mesh.Verts), partial)
+	mesh.Lods = validLods(mesh.Lods, len(mesh.Faces))
+	partial.LodsLost = lost(uint64(header.NumLods), len(mesh.Lods))
+	mesh.MeshSubsets = salvageSubsets(mesh.MeshSubsets, facePrefix, len(mesh.Verts), len(mesh.Faces))
+
+	/* Envelopes are only written for skinned meshes, so they go if the bones did, names and subset bones with them */
+	if len(mesh.Bones) == 0 || len(mesh.Envelopes) < len(mesh.Verts) {
+		mesh.Envelopes = make([]Envelope, len(mesh.Verts))
+		mesh.Bones = mesh.Bones[:0]
+		mesh.NameTable = mesh.NameTable[:0]
+		for i := range mesh.MeshSubsets {
+			subset := &mesh.MeshSubsets[i]
+			subset.NumBonesIndicies = 0
+			for b := range subset.BoneIndicies {
+				subset.BoneIndicies[b] = NoBone
+			}
+		}
+	}
+	partial.BonesLost = lost(uint64(header.NumBones), len(mesh.Bones))
+	partial.NameBytesLost = lost(uint64(header.SizeOf_bone_names_Buffer), len(mesh.NameTable))
+	partial.SubsetsLost = lost(uint64(header.NumSubsets), len(mesh.MeshSubsets))
+
+	header.NumVerts = uint32(len(mesh.Verts))
+	header.NumFaces = uint32(len(mesh.Faces))
+	header.NumLods = ushort(len(mesh.Lods))
+	header.NumBones = ushort(len(mesh.Bones))
+	header.SizeOf_bone_names_Buffer = uint32(len(mesh.NameTable))
+	header.NumSubsets = ushort(len(mesh.MeshSubsets))
+	return partial
+}
@@ -0,0 +1,106 @@
+package mesh_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/MojaveMF/mesh"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestLenientTruncatedFaces(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	facesStart := 13 + 24 + 1880*40 + 1880*8
+	truncated := data[:facesStart+100*12+7]
+
+	if _, err := mesh.DecodeMesh(bytes.NewReader(truncated)); err == nil {
+		t.Error("strict decode should fail")
+	}
+
+	decoded, err := mesh.DecodeMeshOptions(bytes.NewReader(truncated), mesh.DecodeOptions{Lenient: true})
+	partial := &mesh.PartialError{}
+	if !errors.Is(err, mesh.ErrPartialMesh) || !errors.As(err, &partial) {
+		t.Errorf("expected a partial mesh error got %v", err)
+		return
+	}
+	mesh4 := decoded.(*mesh.Mesh4)
+	if len(mesh4.Verts) != 1880 || len(mesh4.Faces) != 100 || mesh4.Header.NumFaces != 100 {
+		t.Errorf("got %d verts %d faces", len(mesh4.Verts), len(mesh4.Faces))
+	}
+	if partial.FacesLost != 6328-100 || partial.BonesLost != 5 || partial.SubsetsLost != 1 {
+		t.Errorf("wrong loss report %+v", partial)
+	}
+	if err := mesh4.Validate().Err(); err != nil {
+		t.Errorf("salvaged mesh is not valid: %s", err)
+	}
+}
+
+func TestLenientTruncatedVerts(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v2")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	/* Half the verts and none of the faces that need the rest */
+	truncated := data[:13+12+940*40+3]
+
+	decoded, err := mesh.DecodeMeshOptions(bytes.NewReader(truncated), mesh.DecodeOptions{Lenient: true})
+	partial := &mesh.PartialError{}
+	if !errors.As(err, &partial) {
+		t.Errorf("expected a partial mesh error got %v", err)
+		return
+	}
+	mesh2 := decoded.(*mesh.Mesh2Rgba)
+	if len(mesh2.Verts) != 940 || partial.VertsLost != 940 || len(mesh2.Faces) != 0 {
+		t.Errorf("got %d verts %d faces %+v", len(mesh2.Verts), len(mesh2.Faces), partial)
+	}
+}
+
+func TestLenientSubsetsFollowDroppedFaces(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+	lod0 := mesh4.Lods[1]
+
+	/* A face into nothing ahead of the first subset, and a second subset that gets cut off */
+	mesh4.Faces = append([]mesh.Face{{A: 0, B: 1, C: 99999}}, mesh4.Faces...)
+	for i := 1; i < len(mesh4.Lods); i++ {
+		mesh4.Lods[i]++
+	}
+	mesh4.MeshSubsets[0].FacesLength++
+	mesh4.MeshSubsets = append(mesh4.MeshSubsets, mesh4.MeshSubsets[0])
+	mesh4.Header.NumFaces++
+	mesh4.Header.NumSubsets++
+	truncated := encode(t, mesh4)
+	truncated = truncated[:len(truncated)-10]
+
+	decoded, err := mesh.DecodeMeshOptions(bytes.NewReader(truncated), mesh.DecodeOptions{Lenient: true})
+	partial := &mesh.PartialError{}
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected a partial mesh error got %v", err)
+	}
+	if partial.DanglingFaces != 1 || partial.SubsetsLost != 1 {
+		t.Errorf("wrong loss report %+v", partial)
+	}
+	salvaged := decoded.(*mesh.Mesh4)
+	if len(salvaged.MeshSubsets) != 1 || salvaged.MeshSubsets[0].FacesBegin != 0 || salvaged.MeshSubsets[0].FacesLength != lod0 {
+		t.Errorf("subset was not moved with the faces %+v", salvaged.MeshSubsets)
+	}
+	if err := salvaged.Validate().Err(); err != nil {
+		t.Errorf("salvaged mesh is not valid: %s", err)
+	}
+}
+
+func TestPartialErrorMessage(t *testing.T) {
+	err := &mesh.PartialError{Err: io.ErrUnexpectedEOF, EnvelopesLost: 3, NameBytesLost: 7}
+	if expected := "partial mesh, lost 3 envelopes 7 name bytes: unexpected EOF"; err.Error() != expected {
+		t.Errorf("expected %q got %q", expected, err.Error())
+	}
+	err = &mesh.PartialError{Err: io.ErrUnexpectedEOF, FacesLost: 2, DanglingFaces: 1}
+	if expected := "partial mesh, lost 3 faces (1 dangling): unexpected EOF"; err.Error() != expected {
+		t.Errorf("expected %q got %q", expected, err.Error())
+	}
+}
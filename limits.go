@@ -0,0 +1,328 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+var ErrLimitExceeded = errors.New("mesh is over the decode limits")
+
+/* Zero means no limit on that field */
+type Limits struct {
+	MaxVerts     uint32
+	MaxFaces     uint32
+	MaxBones     uint16
+	MaxLods      uint16
+	MaxSubsets   uint16
+	MaxNameTable uint32
+
+	/*
+		Everything read including the version line. Stream LoadMesh calls take
+		the line as already read so both ways of decoding count the same bytes
+	*/
+	MaxBytes int64
+}
+
+/* Roomy enough for any avatar or accessory Roblox will take, used unless options say otherwise */
+var DefaultLimits = Limits{
+	MaxVerts:     1 << 20,
+	MaxFaces:     1 << 21,
+	MaxBones:     1024,
+	MaxLods:      32,
+	MaxSubsets:   256,
+	MaxNameTable: 1 << 16,
+	MaxBytes:     128 << 20,
+}
+
+type DecodeOptions struct {
+	Limits Limits
+
+	/*
+		Keep whatever was read in full when the input ends early, the mesh comes
+		back alongside a *PartialError saying what was lost
+	*/
+	Lenient bool
+}
+
+/*
+Field is what went over, "input" means the header asks for more bytes than
+the stream has left. Works with errors.Is(err, ErrLimitExceeded).
+*/
+type LimitError struct {
+	Field string
+	Value uint64
+	Limit uint64
+}
+
+func (E *LimitError) Error() string {
+	if E.Field == "input" {
+		return fmt.Sprintf("mesh declares %d bytes but only %d are left in the input", E.Value, E.Limit)
+	}
+	return fmt.Sprintf("mesh has %d %s which is over the limit of %d", E.Value, E.Field, E.Limit)
+}
+
+func (E *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+func checkLimit(field string, value uint64, limit uint64) error {
+	if limit != 0 && value > limit {
+		return &LimitError{field, value, limit}
+	}
+	return nil
+}
+
+/* Anything that knows how much is left, bytes.Reader and friends */
+type lengther interface {
+	Len() int
+}
+
+func remainingInput(stream io.Reader) int64 {
+	switch typed := stream.(type) {
+	case lengther:
+		return int64(typed.Len())
+	case io.Seeker:
+		current, err := typed.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		end, err := typed.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1
+		}
+		if _, err := typed.Seek(current, io.SeekStart); err != nil {
+			return -1
+		}
+		return end - current
+	default:
+		return -1
+	}
+}
+
+/* Wraps the input for a single decode, counting bytes and enforcing MaxBytes */
+type meshReader struct {
+	stream    io.Reader
+	opts      DecodeOptions
+	version   uint8
+	offset    int64  /* bytes read through this reader */
+	counted   int64  /* bytes read before it that still count against MaxBytes */
+	base      int64  /* where in the file the reader started */
+	remaining int64  /* bytes left when the reader was made, -1 if unknown */
+	truncated error  /* set once a lenient decode runs out of input */
+	data      []byte /* everything the stream will give when it is already in memory */
+	single    [1]byte
+	ctx       context.Context /* only set when it can be cancelled */
+}
+
+/*
+Reuses an existing meshReader so a version line read earlier still counts.
+Stream readers are made after the version line was taken, when the input
+cant tell us its position that line is assumed to be the only thing read.
+*/
+func newMeshReader(stream io.Reader, opts DecodeOptions, version uint8) *meshReader {
+	if existing, ok := stream.(*meshReader); ok {
+		return existing
+	}
+	reader := meshReader{
+		stream:    stream,
+		opts:      opts,
+		version:   version,
+		remaining: remainingInput(stream),
+	}
+	if version != 0 {
+		reader.base = int64(len(versionLine(version)))
+		reader.counted = reader.base
+	}
+	if seeker, ok := stream.(io.Seeker); ok {
+		if position, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			reader.base = position
+		}
+	}
+	return &reader
+}
+
+/* Bytes that count against MaxBytes */
+func (R *meshReader) used() int64 {
+	return R.counted + R.offset
+}
+
+func (R *meshReader) overLimit() error {
+	if limit := R.opts.Limits.MaxBytes; limit > 0 && R.used() >= limit {
+		return &LimitError{"bytes", uint64(R.used()) + 1, uint64(limit)}
+	}
+	return nil
+}
+
+/* The text v1 format and version line are read a byte at a time */
+func (R *meshReader) ReadByte() (byte, error) {
+	if err := R.overLimit(); err != nil {
+		return 0, err
+	}
+	if R.offset%contextChunk == 0 {
+		if err := R.cancelled(); err != nil {
+			return 0, err
+		}
+	}
+	reader, ok := R.stream.(io.ByteReader)
+	if !ok {
+		if _, err := io.ReadFull(R, R.single[:]); err != nil {
+			return 0, err
+		}
+		return R.single[0], nil
+	}
+	value, err := reader.ReadByte()
+	if err == nil {
+		R.offset++
+	}
+	return value, err
+}
+
+func (R *meshReader) Read(data []byte) (int, error) {
+	if err := R.overLimit(); err != nil {
+		return 0, err
+	}
+	/* Big sections are read a chunk at a time so a cancel is noticed part way */
+	if R.ctx != nil {
+		if err := R.cancelled(); err != nil {
+			return 0, err
+		}
+		data = data[:min(len(data), contextChunk)]
+	}
+	if limit := R.opts.Limits.MaxBytes; limit > 0 {
+		if int64(len(data)) > limit-R.used() {
+			data = data[:limit-R.used()]
+		}
+	}
+	read, err := R.stream.Read(data)
+	R.offset += int64(read)
+	return read, err
+}
+
+/* Checked after the header so nothing big gets allocated for a lie */
+func (R *meshReader) checkBody(declared uint64) error {
+	total := uint64(R.used()) + declared
+	if err := checkLimit("bytes", total, uint64(max(R.opts.Limits.MaxBytes, 0))); err != nil {
+		return err
+	}
+	if R.remaining < 0 || R.opts.Lenient {
+		return nil
+	}
+	left := uint64(max(R.remaining-R.offset, 0))
+	if declared > left {
+		return &LimitError{"input", declared, left}
+	}
+	return nil
+}
+
+func (R *meshReader) checkHeader2(header *MeshHeader2) error {
+	limits := R.opts.Limits
+	if err := checkLimit("verts", uint64(header.NumVerts), uint64(limits.MaxVerts)); err != nil {
+		return err
+	}
+	if err := checkLimit("faces", uint64(header.NumFaces), uint64(limits.MaxFaces)); err != nil {
+		return err
+	}
+	return R.checkBody(header.bodySize())
+}
+
+func (R *meshReader) checkHeader3(header *MeshHeader3) error {
+	limits := R.opts.Limits
+	if err := checkLimit("verts", uint64(header.NumVerts), uint64(limits.MaxVerts)); err != nil {
+		return err
+	}
+	if err := checkLimit("faces", uint64(header.NumFaces), uint64(limits.MaxFaces)); err != nil {
+		return err
+	}
+	if err := checkLimit("lods", uint64(header.NumLods), uint64(limits.MaxLods)); err != nil {
+		return err
+	}
+	return R.checkBody(header.bodySize())
+}
+
+func (R *meshReader) checkHeader4(header *MeshHeader4) error {
+	limits := R.opts.Limits
+	checks := []struct {
+		field        string
+		value, limit uint64
+	}{
+		{"verts", uint64(header.NumVerts), uint64(limits.MaxVerts)},
+		{"faces", uint64(header.NumFaces), uint64(limits.MaxFaces)},
+		{"lods", uint64(header.NumLods), uint64(limits.MaxLods)},
+		{"bones", uint64(header.NumBones), uint64(limits.MaxBones)},
+		{"subsets", uint64(header.NumSubsets), uint64(limits.MaxSubsets)},
+		{"name table bytes", uint64(header.SizeOf_bone_names_Buffer), uint64(limits.MaxNameTable)},
+	}
+	for _, check := range checks {
+		if err := checkLimit(check.field, check.value, check.limit); err != nil {
+			return err
+		}
+	}
+	return R.checkBody(header.bodySize())
+}
+
+/* Bytes after the header, 36 byte verts are the only other size the reader takes */
+func (H *MeshHeader2) bodySize() uint64 {
+	vertexSize := uint64(VertexModernSize)
+	if H.VertexSize == 36 {
+		vertexSize = uint64(VertexNoRgbaSize)
+	}
+	return uint64(H.NumVerts)*vertexSize + uint64(H.NumFaces)*uint64(FaceSize)
+}
+
+func (H *MeshHeader3) bodySize() uint64 {
+	return uint64(H.NumVerts)*uint64(VertexModernSize) +
+		uint64(H.NumFaces)*uint64(FaceSize) +
+		uint64(H.NumLods)*4
+}
+
+/* Bytes after the header, envelopes are only stored for skinned meshes */
+func (H *MeshHeader4) bodySize() uint64 {
+	size := uint64(H.NumVerts)*uint64(VertexModernSize) +
+		uint64(H.NumFaces)*uint64(FaceSize) +
+		uint64(H.NumLods)*4 +
+		uint64(H.NumBones)*uint64(BoneSize) +
+		uint64(H.SizeOf_bone_names_Buffer) +
+		uint64(H.NumSubsets)*uint64(MeshSubsetSize)
+	if H.NumBones > 0 {
+		size += uint64(H.NumVerts) * uint64(EnvelopeSize)
+	}
+	return size
+}
+
+/* Shortest text a v1 vertex can be, [0,0,0] three times */
+const minVertexV1Size = 21
+
+func (R *meshReader) checkFaceCount1(faceCount int) error {
+	if faceCount < 0 || uint64(faceCount) > math.MaxUint32/3 {
+		return &LimitError{"faces", uint64(max(faceCount, 0)), math.MaxUint32 / 3}
+	}
+	limits := R.opts.Limits
+	if err := checkLimit("faces", uint64(faceCount), uint64(limits.MaxFaces)); err != nil {
+		return err
+	}
+	if err := checkLimit("verts", uint64(faceCount)*3, uint64(limits.MaxVerts)); err != nil {
+		return err
+	}
+	return R.checkBody(uint64(faceCount) * 3 * minVertexV1Size)
+}
+
+func DecodeMeshOptions(stream io.Reader, opts DecodeOptions) (Mesh, error) {
+	decoded, _, err := decodeReader(newMeshReader(stream, opts, 0), opts)
+	return decoded, err
+}
+
+/* Also hands back the exact version read, DecodeModel keeps it */
+func decodeReader(reader *meshReader, opts DecodeOptions) (Mesh, uint8, error) {
+	start := reader.position()
+	version, err := MeshVersion(reader)
+	if err != nil {
+		return nil, 0, &DecodeError{0, SectionHeader, -1, start, err}
+	}
+	reader.version = version
+
+	decoded, err := decodeMeshOptions(reader, version, opts)
+	return decoded, version, err
+}
@@ -0,0 +1,115 @@
+package mesh_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/MojaveMF/mesh"
+	"io"
+	"os"
+	"testing"
+)
+
+func hugeV2() []byte {
+	buffer := bytes.NewBufferString("version 2.00\n")
+	binary.Write(buffer, binary.LittleEndian, mesh.MeshHeader2{
+		MeshHeaderSize: mesh.Header2Size,
+		VertexSize:     mesh.VertexModernSize,
+		FaceSize:       mesh.FaceSize,
+		NumVerts:       4000000000,
+		NumFaces:       1,
+	})
+	buffer.Write(make([]byte, 5))
+	return buffer.Bytes()
+}
+
+func TestLimitsRemainingInput(t *testing.T) {
+	/* No limits so only the length of the input can stop it */
+	_, err := mesh.DecodeMeshOptions(bytes.NewReader(hugeV2()), mesh.DecodeOptions{})
+	limitErr := &mesh.LimitError{}
+	if !errors.As(err, &limitErr) || limitErr.Field != "input" {
+		t.Errorf("expected input limit error got %v", err)
+	}
+	if !errors.Is(err, mesh.ErrLimitExceeded) {
+		t.Error("limit error does not match ErrLimitExceeded")
+	}
+}
+
+func TestLimitsFields(t *testing.T) {
+	/* MultiReader hides the length so only the limits can stop it */
+	stream := io.MultiReader(bytes.NewReader(hugeV2()))
+	_, err := mesh.DecodeMeshOptions(stream, mesh.DecodeOptions{Limits: mesh.DefaultLimits})
+	limitErr := &mesh.LimitError{}
+	if !errors.As(err, &limitErr) || limitErr.Field != "verts" {
+		t.Errorf("expected verts limit error got %v", err)
+	}
+
+	file, err := os.Open("./testdata/output.v4")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer file.Close()
+	_, err = mesh.DecodeMeshOptions(file, mesh.DecodeOptions{Limits: mesh.Limits{MaxBones: 2}})
+	if !errors.As(err, &limitErr) || limitErr.Field != "bones" || limitErr.Value != 5 {
+		t.Errorf("expected bones limit error got %v", err)
+	}
+}
+
+func TestLimitsMaxBytes(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v3")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	opts := mesh.DecodeOptions{Limits: mesh.Limits{MaxBytes: int64(len(data))}}
+	if _, err := mesh.DecodeMeshOptions(bytes.NewReader(data), opts); err != nil {
+		t.Errorf("exact size should decode, got %v", err)
+	}
+
+	opts.Limits.MaxBytes--
+	if _, err := mesh.DecodeMeshOptions(io.MultiReader(bytes.NewReader(data)), opts); !errors.Is(err, mesh.ErrLimitExceeded) {
+		t.Errorf("expected byte limit error got %v", err)
+	}
+}
+
+func TestLimitsDefault(t *testing.T) {
+	_, err := mesh.DecodeMesh(io.MultiReader(bytes.NewReader(hugeV2())))
+	limitErr := &mesh.LimitError{}
+	if !errors.As(err, &limitErr) || limitErr.Field != "verts" {
+		t.Errorf("expected verts limit error got %v", err)
+	}
+}
+
+func TestLimitsUnknownLength(t *testing.T) {
+	/* Nothing stops the header here, the verts have to run out instead of being allocated up front */
+	_, err := mesh.DecodeMeshOptions(io.MultiReader(bytes.NewReader(hugeV2())), mesh.DecodeOptions{})
+	decodeErr := &mesh.DecodeError{}
+	if !errors.As(err, &decodeErr) || decodeErr.Section != mesh.SectionVertices || decodeErr.Index != 0 {
+		t.Errorf("expected verts to run out got %v", err)
+	}
+}
+
+func TestLimitsMaxBytesStream(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	/* Loading the stream directly still counts the version line */
+	load := func(limit int64) error {
+		stream := bytes.NewReader(data)
+		if _, err := mesh.MeshVersion(stream); err != nil {
+			return err
+		}
+		reader := mesh.MeshStream4{Stream: io.MultiReader(stream)}
+		_, err := reader.LoadMeshOptions(mesh.DecodeOptions{Limits: mesh.Limits{MaxBytes: limit}})
+		return err
+	}
+	if err := load(int64(len(data))); err != nil {
+		t.Errorf("exact size should decode, got %v", err)
+	}
+	if err := load(int64(len(data)) - 1); !errors.Is(err, mesh.ErrLimitExceeded) {
+		t.Errorf("expected byte limit error got %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrLossyConversion = errors.New("conversion would lose data")
+
+/* What a conversion can throw away or only keep roughly */
+type LossCategory string
+
+const (
+	LossTangents  LossCategory = "tangents"  /* verts with a tangent, v1 has none */
+	LossColors    LossCategory = "colors"    /* verts with a color other than the default */
+	LossFaces     LossCategory = "faces"     /* faces past LOD 0 */
+	LossLods      LossCategory = "lods"      /* levels past LOD 0 */
+	LossBones     LossCategory = "bones"     /* bones, names included */
+	LossEnvelopes LossCategory = "envelopes" /* verts with skin weights */
+	LossSubsets   LossCategory = "subsets"   /* subsets */
+	LossHeader    LossCategory = "header"    /* v4 only header fields that were set */
+	LossPrecision LossCategory = "precision" /* verts written as text with 6 decimals */
+)
+
+type Loss struct {
+	Category LossCategory
+	Count    int
+
+	/* Still there but not exactly, like rounded floats or colors that were made up */
+	Approximate bool
+}
+
+/* Empty when nothing changes */
+type LossReport []Loss
+
+func (R *LossReport) add(category LossCategory, count int, approximate bool) {
+	if count > 0 {
+		*R = append(*R, Loss{category, count, approximate})
+	}
+}
+
+/* Replaces the count for a category, 0 removes it */
+func (R *LossReport) set(category LossCategory, count int, approximate bool) {
+	kept := (*R)[:0]
+	for _, loss := range *R {
+		if loss.Category != category {
+			kept = append(kept, loss)
+		}
+	}
+	*R = kept
+	R.add(category, count, approximate)
+}
+
+/* True when anything was thrown away, approximations dont count */
+func (R LossReport) Lossy() bool {
+	for _, loss := range R {
+		if !loss.Approximate {
+			return true
+		}
+	}
+	return false
+}
+
+func (R LossReport) Count(category LossCategory) int {
+	total := 0
+	for _, loss := range R {
+		if loss.Category == category {
+			total += loss.Count
+		}
+	}
+	return total
+}
+
+func (R LossReport) String() string {
+	if len(R) == 0 {
+		return "nothing lost"
+	}
+	parts := make([]string, len(R))
+	for i, loss := range R {
+		parts[i] = fmt.Sprintf("%d %s", loss.Count, loss.Category)
+		if loss.Approximate {
+			parts[i] += " approximated"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+/* Returned instead of converting when FailOnLoss is set, works with errors.Is(err, ErrLossyConversion) */
+type LossError struct {
+	Version uint8
+	Report  LossReport
+}
+
+func (E *LossError) Error() string {
+	version, err := MeshHeader(E.Version)
+	if err != nil {
+		version = "unknown version"
+	}
+	return fmt.Sprintf("converting to %s loses %s", version, E.Report)
+}
+
+func (E *LossError) Is(target error) bool {
+	return target == ErrLossyConversion
+}
+
+type ExportOptions struct {
+	FailOnLoss bool /* return a *LossError instead of a mesh that lost something */
+}
+
+/* What exporting mesh to version would drop, nothing is converted */
+func ExportLoss(mesh Mesh, version uint8) LossReport {
+	model, err := NewModel(mesh)
+	if err != nil {
+		model = mesh.ExportV4().ToModel()
+	}
+	report := LossReport{}
+	family := versionFamily(version)
+	if family == 0 {
+		return report
+	}
+	if family != MeshVersion1 && family != MeshVersion2 && !model.Present.Has(AttributeColors) {
+		/* Made up since the output stores them */
+		report.add(LossColors, len(model.Positions), true)
+	}
+	if family == MeshVersion4 {
+		return report
+	}
+
+	/* Only v4 skins and only v3 and v4 keep every level */
+	report.add(LossBones, len(model.Bones), false)
+	report.add(LossEnvelopes, skinned(model.Skin), false)
+	report.add(LossSubsets, len(model.Subsets), false)
+	if model.LodType != 0 || model.HighQualityLods != 0 {
+		report.add(LossHeader, 1, false)
+	}
+	if family != MeshVersion3 {
+		report.add(LossLods, len(model.LodRanges())-1, false)
+		report.add(LossFaces, len(model.Faces)-len(model.normalFaces()), false)
+	}
+
+	if family == MeshVersion1 {
+		if model.Present.Has(AttributeTangents) {
+			report.add(LossTangents, countVerts(model.Tangents, Tangent{}), false)
+		}
+		if model.Present.Has(AttributeColors) {
+			report.add(LossColors, countVerts(model.Colors, DefaultColor), false)
+		}
+		report.add(LossPrecision, len(model.Positions), true)
+	}
+	return report
+}
+
+func skinned(skin []SkinWeights) int {
+	count := 0
+	for _, weights := range skin {
+		if weights.Weights != [4]byte{} {
+			count++
+		}
+	}
+	return count
+}
+
+/* Items that hold something other than empty */
+func countVerts[T comparable](items []T, empty T) int {
+	count := 0
+	for _, item := range items {
+		if item != empty {
+			count++
+		}
+	}
+	return count
+}
+
+func checkLoss(mesh Mesh, version uint8, opts ExportOptions) (LossReport, error) {
+	report := ExportLoss(mesh, version)
+	if opts.FailOnLoss && report.Lossy() {
+		return report, &LossError{version, report}
+	}
+	return report, nil
+}
+
+/*
+EncodeMeshVersion that says what was lost on the way. v1 isnt a Mesh so
+use ExportV1Loss for it.
+*/
+func ExportVersion(mesh Mesh, version uint8, opts ExportOptions) (Mesh, LossReport, error) {
+	report, err := checkLoss(mesh, version, opts)
+	if err != nil {
+		return nil, report, err
+	}
+	exported := EncodeMeshVersion(mesh, version)
+	if exported == nil {
+		return nil, report, ErrBadMeshVersion
+	}
+	return exported, report, nil
+}
+
+/*
+The ExportV1 to ExportV4 methods drop what the version cant hold without
+saying, these report it. They convert the same way the encoders do so the
+report describes the mesh that comes back.
+*/
+func ExportV1Loss(mesh Mesh, opts ExportOptions) (*Mesh1, LossReport, error) {
+	report, err := checkLoss(mesh, MeshVersion1_01, opts)
+	if err != nil {
+		return nil, report, err
+	}
+	mesh1, err := exportV1(mesh)
+	return mesh1, report, err
+}
+
+func ExportV2Loss(mesh Mesh, opts ExportOptions) (Mesh2, LossReport, error) {
+	report, err := checkLoss(mesh, MeshVersion2, opts)
+	if err != nil {
+		return nil, report, err
+	}
+	mesh2, err := exportV2(mesh)
+	return mesh2, report, err
+}
+
+func ExportV3Loss(mesh Mesh, opts ExportOptions) (*Mesh3, LossReport, error) {
+	report, err := checkLoss(mesh, MeshVersion3, opts)
+	if err != nil {
+		return nil, report, err
+	}
+	mesh3, err := exportV3(mesh)
+	return mesh3, report, err
+}
+
+func ExportV4Loss(mesh Mesh, opts ExportOptions) (*Mesh4, LossReport, error) {
+	report, err := checkLoss(mesh, MeshVersion4, opts)
+	if err != nil {
+		return nil, report, err
+	}
+	mesh4, err := exportV4(mesh)
+	return mesh4, report, err
+}
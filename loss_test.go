@@ -0,0 +1,134 @@
+package mesh_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/MojaveMF/mesh"
+	"testing"
+)
+
+func TestExportLoss(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+
+	if report := mesh.ExportLoss(mesh4, mesh.MeshVersion4_1); len(report) != 0 || report.String() != "nothing lost" {
+		t.Errorf("expected nothing lost going to 4.01 got %s", report)
+	}
+
+	report := mesh.ExportLoss(mesh4, mesh.MeshVersion2)
+	for category, expected := range map[mesh.LossCategory]int{
+		mesh.LossBones:    int(mesh4.Header.NumBones),
+		mesh.LossSubsets:  len(mesh4.MeshSubsets),
+		mesh.LossLods:     len(mesh4.Lods) - 2,
+		mesh.LossFaces:    len(mesh4.Faces) - len(mesh4.GetNormalFaces()),
+		mesh.LossTangents: 0,
+	} {
+		if count := report.Count(category); count != expected {
+			t.Errorf("%s: expected %d lost got %d", category, expected, count)
+		}
+	}
+	if report.Count(mesh.LossEnvelopes) == 0 || !report.Lossy() {
+		t.Errorf("expected skin weights to be lost got %s", report)
+	}
+
+	/* v3 keeps every level */
+	if report := mesh.ExportLoss(mesh4, mesh.MeshVersion3); report.Count(mesh.LossLods) != 0 || report.Count(mesh.LossBones) == 0 {
+		t.Errorf("unexpected v3 report %s", report)
+	}
+
+	report = mesh.ExportLoss(mustDecode(t, noRgbaMesh()), mesh.MeshVersion3)
+	if report.Lossy() || report.Count(mesh.LossColors) != 6 {
+		t.Errorf("expected made up colors only got %s", report)
+	}
+	report = mesh.ExportLoss(mustDecode(t, noRgbaMesh()), mesh.MeshVersion1_01)
+	if report.Count(mesh.LossPrecision) != 6 || report.Count(mesh.LossColors) != 0 {
+		t.Errorf("unexpected v1 report %s", report)
+	}
+}
+
+func TestFailOnLoss(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4")
+
+	exported, report, err := mesh.ExportVersion(mesh4, mesh.MeshVersion2, mesh.ExportOptions{FailOnLoss: true})
+	var lossErr *mesh.LossError
+	if exported != nil || !errors.Is(err, mesh.ErrLossyConversion) || !errors.As(err, &lossErr) {
+		t.Fatalf("expected a loss error got %v", err)
+	}
+	if lossErr.Version != mesh.MeshVersion2 || len(lossErr.Report) != len(report) {
+		t.Errorf("error and report disagree")
+	}
+	if exported, _, err := mesh.ExportVersion(mesh4, mesh.MeshVersion2, mesh.ExportOptions{}); err != nil || exported == nil {
+		t.Errorf("expected the export to go ahead got %v", err)
+	}
+	if _, _, err := mesh.ExportVersion(mesh4, mesh.MeshVersion4_1, mesh.ExportOptions{FailOnLoss: true}); err != nil {
+		t.Errorf("expected nothing lost got %v", err)
+	}
+
+	output := bytes.Buffer{}
+	encoder := mesh.NewEncoder(&output, mesh.EncodeVersion(mesh.MeshVersion3), mesh.EncodeFailOnLoss(true))
+	if err := encoder.Encode(mesh4); !errors.Is(err, mesh.ErrLossyConversion) || output.Len() != 0 {
+		t.Errorf("expected nothing written got %d bytes and %v", output.Len(), err)
+	}
+	if encoder.LossReport().Count(mesh.LossBones) == 0 {
+		t.Errorf("expected the encoder to report lost bones")
+	}
+
+	painted := defaultColored()
+	painted.Verts[0].G = 0
+	encoder = mesh.NewEncoder(&output, mesh.EncodeVersion(mesh.MeshVersion2), mesh.EncodeFailOnLoss(true),
+		mesh.EncodeColors(mesh.ColorOptions{Layout: mesh.ColorLayoutNoRgba}))
+	if err := encoder.Encode(painted); !errors.Is(err, mesh.ErrLossyConversion) || encoder.LossReport().Count(mesh.LossColors) != 1 {
+		t.Errorf("expected a dropped color to fail got %v", err)
+	}
+	encoder = mesh.NewEncoder(&output, mesh.EncodeVersion(mesh.MeshVersion2), mesh.EncodeFailOnLoss(true))
+	if err := encoder.Encode(painted); err != nil {
+		t.Errorf("expected 40 byte verts to keep the color got %v", err)
+	}
+}
+
+func TestExportVersionLoss(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+
+	mesh1, report, err := mesh.ExportV1Loss(mesh4, mesh.ExportOptions{})
+	if err != nil || mesh1 == nil || report.Count(mesh.LossBones) != 5 || report.Count(mesh.LossPrecision) == 0 {
+		t.Errorf("expected a v1 mesh and its losses got %s %v", report, err)
+	}
+	if mesh2, _, err := mesh.ExportV2Loss(mesh4, mesh.ExportOptions{FailOnLoss: true}); mesh2 != nil || !errors.Is(err, mesh.ErrLossyConversion) {
+		t.Errorf("expected v2 to fail on loss got %v", err)
+	}
+	if mesh3, report, err := mesh.ExportV3Loss(mesh4, mesh.ExportOptions{}); err != nil || mesh3 == nil || report.Count(mesh.LossLods) != 0 {
+		t.Errorf("unexpected v3 report %s %v", report, err)
+	}
+	if exported, report, err := mesh.ExportV4Loss(mesh4, mesh.ExportOptions{FailOnLoss: true}); err != nil || exported != mesh4 || len(report) != 0 {
+		t.Errorf("v4 to v4 should lose nothing got %s %v", report, err)
+	}
+}
+
+func TestExportVersionLossSameConversion(t *testing.T) {
+	lodless := twoTriangles()
+	lodless.Lods, lodless.Header.NumLods = nil, 0
+
+	/* The typed exports have to hand back what ExportVersion and the report describe */
+	for _, source := range []mesh.Mesh{decodeFile(t, "./testdata/output.v4"), twoTriangles().ExportV2(), lodless} {
+		mesh2, _, err := mesh.ExportV2Loss(source, mesh.ExportOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mesh3, _, err := mesh.ExportV3Loss(source, mesh.ExportOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mesh4, _, err := mesh.ExportV4Loss(source, mesh.ExportOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for version, exported := range map[uint8]mesh.Mesh{mesh.MeshVersion2: mesh2, mesh.MeshVersion3: mesh3, mesh.MeshVersion4: mesh4} {
+			expected, _, err := mesh.ExportVersion(source, version, mesh.ExportOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encode(t, exported), encode(t, expected)) {
+				t.Errorf("%T to %d: typed export differs from ExportVersion", source, version)
+			}
+		}
+	}
+}
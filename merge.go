@@ -0,0 +1,474 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNothingToMerge     = errors.New("no meshes were given to merge")
+	ErrTooManySubsetBones = fmt.Errorf("a single subset references more than %d bones", MaxSubsetBones)
+)
+
+/* A run of verts and lod0 faces that has to stay in one subset */
+type mergeUnit struct {
+	vertsBegin, vertsLength uint32
+	facesBegin, facesLength uint32
+	bones                   []ushort /* global bone indicies */
+}
+
+/*
+Combines meshes into a single Mesh4. Faces are grouped by lod level so every
+level of every mesh stays a level in the output, bones are shared by name and
+subsets are packed together until they would go over MaxSubsetBones.
+*/
+func Merge(meshes ...Mesh) (*Mesh4, error) {
+	if len(meshes) == 0 {
+		return nil, ErrNothingToMerge
+	}
+
+	sources := make([]*Mesh4, len(meshes))
+	levels := 0
+	for i, source := range meshes {
+		sources[i] = splitSkinning(source.ExportV4())
+		levels = max(levels, len(lodRanges(sources[i].Lods, len(sources[i].Faces))))
+	}
+
+	merged := Mesh4{
+		Verts:       make([]VertexModern, 0),
+		Envelopes:   make([]Envelope, 0),
+		Faces:       make([]Face, 0),
+		Lods:        make([]uint32, 0, levels+1),
+		Bones:       make([]Bone, 0),
+		NameTable:   make([]byte, 0),
+		MeshSubsets: make([]MeshSubset, 0),
+	}
+
+	/* Skeletons are unioned by name, the first mesh to define a bone wins. Unnamed bones are never shared */
+	boneNames := make(map[string]ushort)
+	boneMaps := make([][]ushort, len(sources))
+	for i, source := range sources {
+		boneMaps[i] = make([]ushort, len(source.Bones))
+		added := make([]bool, len(source.Bones))
+		for b := range source.Bones {
+			name := source.BoneName(b)
+			if index, ok := boneNames[name]; ok && name != "" {
+				boneMaps[i][b] = index
+				continue
+			}
+			index := ushort(len(merged.Bones))
+			if name != "" {
+				boneNames[name] = index
+			}
+			boneMaps[i][b] = index
+			added[b] = true
+
+			bone := source.Bones[b]
+			bone.BoneNameIndex = uint32(len(merged.NameTable))
+			merged.Bones = append(merged.Bones, bone)
+			merged.NameTable = append(merged.NameTable, name...)
+			merged.NameTable = append(merged.NameTable, 0)
+		}
+		for b, bone := range source.Bones {
+			if !added[b] {
+				continue
+			}
+			target := &merged.Bones[boneMaps[i][b]]
+			target.ParentIndex = remapBone(boneMaps[i], bone.ParentIndex)
+			target.LodParentIndex = remapBone(boneMaps[i], bone.LodParentIndex)
+		}
+	}
+	if len(merged.Bones) > int(NoBone) {
+		return nil, fmt.Errorf("merged skeleton has %d bones", len(merged.Bones))
+	}
+
+	vertOffsets := make([]uint32, len(sources))
+	for i, source := range sources {
+		vertOffsets[i] = uint32(len(merged.Verts))
+		merged.Verts = append(merged.Verts, source.Verts...)
+	}
+
+	/* levelStarts[mesh][level] is where that meshes faces for the level landed */
+	levelStarts := make([][]uint32, len(sources))
+	for level := 0; level < levels; level++ {
+		merged.Lods = append(merged.Lods, uint32(len(merged.Faces)))
+		for i, source := range sources {
+			ranges := lodRanges(source.Lods, len(source.Faces))
+			if level >= len(ranges) {
+				continue
+			}
+			levelStarts[i] = append(levelStarts[i], uint32(len(merged.Faces)))
+			for _, face := range source.Faces[ranges[level][0]:ranges[level][1]] {
+				merged.Faces = append(merged.Faces, Face{
+					face.A + vertOffsets[i],
+					face.B + vertOffsets[i],
+					face.C + vertOffsets[i],
+				})
+			}
+		}
+	}
+	merged.Lods = append(merged.Lods, uint32(len(merged.Faces)))
+
+	if len(merged.Bones) > 0 {
+		if err := mergeSkinning(&merged, sources, boneMaps, vertOffsets, levelStarts); err != nil {
+			return nil, err
+		}
+	}
+
+	merged.Header = MeshHeader4{
+		SizeOf_MeshHeader:        Header4Size,
+		LodType:                  sources[0].Header.LodType,
+		NumVerts:                 uint32(len(merged.Verts)),
+		NumFaces:                 uint32(len(merged.Faces)),
+		NumLods:                  ushort(len(merged.Lods)),
+		NumBones:                 ushort(len(merged.Bones)),
+		SizeOf_bone_names_Buffer: uint32(len(merged.NameTable)),
+		NumSubsets:               ushort(len(merged.MeshSubsets)),
+		NumHighQualityLods:       sources[0].Header.NumHighQualityLods,
+	}
+
+	return &merged, nil
+}
+
+func remapBone(boneMap []ushort, index ushort) ushort {
+	if int(index) >= len(boneMap) {
+		return NoBone
+	}
+	return boneMap[index]
+}
+
+/* Moves a face index from the source mesh into the merged face list */
+func mergedFace(source *Mesh4, starts []uint32, face uint32) uint32 {
+	for level, lodRange := range lodRanges(source.Lods, len(source.Faces)) {
+		if face >= lodRange[0] && face < lodRange[1] {
+			return starts[level] + face - lodRange[0]
+		}
+	}
+	return starts[0] + face
+}
+
+func mergeSkinning(merged *Mesh4, sources []*Mesh4, boneMaps [][]ushort, vertOffsets []uint32, levelStarts [][]uint32) error {
+	/* Envelopes are pointed at global bones first then made subset local again */
+	influences := make([][4]ushort, len(merged.Verts))
+	weights := make([][4]byte, len(merged.Verts))
+	units := make([]mergeUnit, 0)
+
+	for i, source := range sources {
+		offset := vertOffsets[i]
+		lod0 := lodRanges(source.Lods, len(source.Faces))[0]
+
+		if len(source.MeshSubsets) == 0 {
+			/* Unsubsetted meshes index bones directly */
+			unit := mergeUnit{
+				vertsBegin:  offset,
+				vertsLength: uint32(len(source.Verts)),
+				facesBegin:  levelStarts[i][0],
+				facesLength: lod0[1] - lod0[0],
+			}
+			seen := make(map[ushort]bool)
+			for v := range source.Verts {
+				if v >= len(source.Envelopes) || len(source.Bones) == 0 {
+					break
+				}
+				envelope := source.Envelopes[v]
+				for slot := 0; slot < 4; slot++ {
+					global := remapBone(boneMaps[i], ushort(envelope.Bones[slot]))
+					influences[offset+uint32(v)][slot] = global
+					weights[offset+uint32(v)][slot] = envelope.Weights[slot]
+					if envelope.Weights[slot] > 0 && global != NoBone && !seen[global] {
+						seen[global] = true
+						unit.bones = append(unit.bones, global)
+					}
+				}
+			}
+			units = append(units, unit)
+			continue
+		}
+
+		for _, subset := range source.MeshSubsets {
+			unit := mergeUnit{
+				vertsBegin:  offset + subset.VertsBegin,
+				vertsLength: subset.VertsLength,
+			}
+			if subset.FacesLength > 0 {
+				unit.facesBegin = mergedFace(source, levelStarts[i], subset.FacesBegin)
+				unit.facesLength = mergedFace(source, levelStarts[i], subset.FacesBegin+subset.FacesLength-1) + 1 - unit.facesBegin
+			}
+
+			local := make([]ushort, 0, subset.NumBonesIndicies)
+			for b := uint32(0); b < subset.NumBonesIndicies && b < MaxSubsetBones; b++ {
+				local = append(local, remapBone(boneMaps[i], subset.BoneIndicies[b]))
+			}
+			unit.bones = local
+
+			end := min(subset.VertsBegin+subset.VertsLength, uint32(len(source.Envelopes)))
+			for v := subset.VertsBegin; v < end; v++ {
+				envelope := source.Envelopes[v]
+				for slot := 0; slot < 4; slot++ {
+					global := NoBone
+					if int(envelope.Bones[slot]) < len(local) {
+						global = local[envelope.Bones[slot]]
+					}
+					influences[offset+v][slot] = global
+					weights[offset+v][slot] = envelope.Weights[slot]
+				}
+			}
+			units = append(units, unit)
+		}
+	}
+
+	/* Pack neighbouring units into one subset while the bones still fit */
+	groups := make([]mergeUnit, 0, len(units))
+	for _, unit := range units {
+		if len(unit.bones) > MaxSubsetBones {
+			return ErrTooManySubsetBones
+		}
+		if len(groups) > 0 {
+			group := &groups[len(groups)-1]
+			bones := unionBones(group.bones, unit.bones)
+			adjacent := group.vertsBegin+group.vertsLength == unit.vertsBegin &&
+				group.facesBegin+group.facesLength == unit.facesBegin
+			if adjacent && len(bones) <= MaxSubsetBones {
+				group.bones = bones
+				group.vertsLength += unit.vertsLength
+				group.facesLength += unit.facesLength
+				continue
+			}
+		}
+		unit.bones = unionBones(nil, unit.bones)
+		groups = append(groups, unit)
+	}
+
+	merged.Envelopes = make([]Envelope, len(merged.Verts))
+	for _, group := range groups {
+		subset := MeshSubset{
+			FacesBegin:       group.facesBegin,
+			FacesLength:      group.facesLength,
+			VertsBegin:       group.vertsBegin,
+			VertsLength:      group.vertsLength,
+			NumBonesIndicies: uint32(len(group.bones)),
+		}
+		local := make(map[ushort]byte, len(group.bones))
+		for b := range subset.BoneIndicies {
+			subset.BoneIndicies[b] = NoBone
+			if b < len(group.bones) {
+				subset.BoneIndicies[b] = group.bones[b]
+				local[group.bones[b]] = byte(b)
+			}
+		}
+		merged.MeshSubsets = append(merged.MeshSubsets, subset)
+
+		for v := group.vertsBegin; v < group.vertsBegin+group.vertsLength && v < uint32(len(merged.Verts)); v++ {
+			for slot := 0; slot < 4; slot++ {
+				merged.Envelopes[v].Bones[slot] = local[influences[v][slot]]
+				merged.Envelopes[v].Weights[slot] = weights[v][slot]
+			}
+		}
+	}
+
+	return nil
+}
+
+/* A run of lod0 faces whose verts fit in one subset */
+type splitGroup struct {
+	facesBegin, facesEnd uint32
+	verts                []uint32
+	bones                []ushort
+}
+
+/*
+Unsubsetted meshes index the skeleton directly so they can reference more bones
+than a subset holds. Those get split into subsets greedily by the bones each
+lod0 face uses, verts shared between two subsets are copied so every subset
+owns its range.
+*/
+func splitSkinning(source *Mesh4) *Mesh4 {
+	if len(source.MeshSubsets) > 0 || len(source.Bones) == 0 {
+		return source
+	}
+
+	skin := make([]SkinWeights, len(source.Verts))
+	all := make([]ushort, 0)
+	for v := range min(len(source.Verts), len(source.Envelopes)) {
+		envelope := source.Envelopes[v]
+		for slot := 0; slot < 4; slot++ {
+			skin[v].Bones[slot] = NoBone
+			if envelope.Weights[slot] > 0 && int(envelope.Bones[slot]) < len(source.Bones) {
+				skin[v].Bones[slot] = ushort(envelope.Bones[slot])
+				skin[v].Weights[slot] = envelope.Weights[slot]
+			}
+		}
+		all = unionBones(all, skin[v].Bones[:])
+	}
+	if len(all) <= MaxSubsetBones {
+		return source
+	}
+	return partitionSkin(source, skin)
+}
+
+/*
+Rebuilds the subsets of source from skin, which points at source.Bones
+directly. Subsets already on source are ignored.
+*/
+func partitionSkin(source *Mesh4, skin []SkinWeights) *Mesh4 {
+	numVerts := uint32(len(source.Verts))
+	vertBones := make([][]ushort, numVerts)
+	for v := range min(len(source.Verts), len(skin)) {
+		for slot := 0; slot < 4; slot++ {
+			if skin[v].Weights[slot] > 0 && int(skin[v].Bones[slot]) < len(source.Bones) {
+				vertBones[v] = append(vertBones[v], skin[v].Bones[slot])
+			}
+		}
+	}
+
+	lod0 := lodRanges(source.Lods, len(source.Faces))[0]
+	groups := make([]splitGroup, 0)
+	current := splitGroup{facesBegin: lod0[0], facesEnd: lod0[0]}
+	faceBones := func(face Face) []ushort {
+		var bones []ushort
+		for _, v := range [3]uint32{face.A, face.B, face.C} {
+			if v < numVerts {
+				bones = unionBones(bones, vertBones[v])
+			}
+		}
+		return bones
+	}
+	for f := lod0[0]; f < lod0[1]; f++ {
+		used := faceBones(source.Faces[f])
+		bones := unionBones(current.bones, used)
+		if len(bones) > MaxSubsetBones && current.facesEnd > current.facesBegin {
+			groups = append(groups, current)
+			current = splitGroup{facesBegin: f}
+			bones = used
+		}
+		current.bones = bones
+		current.facesEnd = f + 1
+	}
+
+	/* Verts only lower lods use still need a subset for their envelopes */
+	placed := make([]bool, numVerts)
+	for _, face := range source.Faces[lod0[0]:lod0[1]] {
+		for _, v := range [3]uint32{face.A, face.B, face.C} {
+			if v < numVerts {
+				placed[v] = true
+			}
+		}
+	}
+	for v := range numVerts {
+		if placed[v] {
+			continue
+		}
+		bones := unionBones(current.bones, vertBones[v])
+		if len(bones) > MaxSubsetBones {
+			groups = append(groups, current)
+			current = splitGroup{facesBegin: lod0[1], facesEnd: lod0[1]}
+			bones = unionBones(nil, vertBones[v])
+		}
+		current.bones = bones
+		current.verts = append(current.verts, v)
+	}
+	groups = append(groups, current)
+
+	split := *source
+	split.Verts = make([]VertexModern, 0, numVerts)
+	split.Envelopes = make([]Envelope, 0, numVerts)
+	split.Faces = make([]Face, len(source.Faces))
+	split.MeshSubsets = make([]MeshSubset, 0, len(groups))
+	primary := make([]uint32, numVerts)
+	for v := range primary {
+		primary[v] = ^uint32(0)
+	}
+
+	for _, group := range groups {
+		begin := uint32(len(split.Verts))
+		local := make(map[ushort]byte, len(group.bones))
+		for b, bone := range group.bones {
+			local[bone] = byte(b)
+		}
+		copies := make(map[uint32]uint32)
+		place := func(v uint32) uint32 {
+			if v >= numVerts {
+				return v
+			}
+			if index, ok := copies[v]; ok {
+				return index
+			}
+			index := uint32(len(split.Verts))
+			copies[v] = index
+			if primary[v] == ^uint32(0) {
+				primary[v] = index
+			}
+			split.Verts = append(split.Verts, source.Verts[v])
+
+			var envelope Envelope
+			if int(v) < len(skin) {
+				for slot := 0; slot < 4; slot++ {
+					bone, ok := local[skin[v].Bones[slot]]
+					if ok && skin[v].Weights[slot] > 0 {
+						envelope.Bones[slot] = bone
+						envelope.Weights[slot] = skin[v].Weights[slot]
+					}
+				}
+			}
+			split.Envelopes = append(split.Envelopes, envelope)
+			return index
+		}
+
+		for f := group.facesBegin; f < group.facesEnd; f++ {
+			face := source.Faces[f]
+			split.Faces[f] = Face{place(face.A), place(face.B), place(face.C)}
+		}
+		for _, v := range group.verts {
+			place(v)
+		}
+
+		subset := MeshSubset{
+			FacesBegin:       group.facesBegin,
+			FacesLength:      group.facesEnd - group.facesBegin,
+			VertsBegin:       begin,
+			VertsLength:      uint32(len(split.Verts)) - begin,
+			NumBonesIndicies: uint32(len(group.bones)),
+		}
+		for b := range subset.BoneIndicies {
+			subset.BoneIndicies[b] = NoBone
+			if b < len(group.bones) {
+				subset.BoneIndicies[b] = group.bones[b]
+			}
+		}
+		split.MeshSubsets = append(split.MeshSubsets, subset)
+	}
+
+	/* Lower lods are not in any subset so they just use the first copy */
+	remap := func(v uint32) uint32 {
+		if v >= numVerts {
+			return v
+		}
+		return primary[v]
+	}
+	for f, face := range source.Faces {
+		if uint32(f) >= lod0[0] && uint32(f) < lod0[1] {
+			continue
+		}
+		split.Faces[f] = Face{remap(face.A), remap(face.B), remap(face.C)}
+	}
+
+	split.Header.NumVerts = uint32(len(split.Verts))
+	split.Header.NumSubsets = ushort(len(split.MeshSubsets))
+	return &split
+}
+
+/* Keeps order so existing local indicies of a stay valid */
+func unionBones(a, b []ushort) []ushort {
+	out := make([]ushort, 0, len(a)+len(b))
+	seen := make(map[ushort]bool, len(a)+len(b))
+	for _, list := range [][]ushort{a, b} {
+		for _, bone := range list {
+			if bone == NoBone || seen[bone] {
+				continue
+			}
+			seen[bone] = true
+			out = append(out, bone)
+		}
+	}
+	return out
+}
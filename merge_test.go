@@ -0,0 +1,172 @@
+package mesh_test
+
+import (
+	"fmt"
+	"github.com/MojaveMF/mesh"
+	"os"
+	"testing"
+)
+
+func decodeFile(t *testing.T, path string) mesh.Mesh {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	meshData, err := mesh.DecodeMesh(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return meshData
+}
+
+func TestMerge(t *testing.T) {
+	mesh3 := decodeFile(t, "./testdata/output.v3").(*mesh.Mesh3)
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+	other := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+
+	merged, err := mesh.Merge(mesh4, other, mesh3)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(merged.Verts) != len(mesh4.Verts)*2+len(mesh3.Verts) {
+		t.Errorf("got %d verts", len(merged.Verts))
+	}
+	if len(merged.Faces) != len(mesh4.Faces)*2+len(mesh3.Faces) {
+		t.Errorf("got %d faces", len(merged.Faces))
+	}
+	if len(merged.Lods) != len(mesh4.Lods) || merged.Lods[1] != mesh4.Lods[1]*2+mesh3.Lods[1] {
+		t.Errorf("lods were not interleaved %v", merged.Lods)
+	}
+	if merged.Faces[mesh4.Lods[1]].A != mesh4.Faces[0].A+uint32(len(mesh4.Verts)) {
+		t.Error("second mesh faces were not offset")
+	}
+
+	/* Same skeleton twice should not add bones or subsets */
+	if len(merged.Bones) != len(mesh4.Bones) {
+		t.Errorf("got %d bones", len(merged.Bones))
+	}
+	if len(merged.MeshSubsets) != 1 || merged.MeshSubsets[0].VertsLength != uint32(len(merged.Verts)) {
+		t.Errorf("subsets were not packed %+v", merged.MeshSubsets)
+	}
+	if merged.BoneName(4) != "Head" {
+		t.Errorf("name table broken, got %q", merged.BoneName(4))
+	}
+	if merged.Envelopes[len(mesh4.Verts)+1] != mesh4.Envelopes[1] {
+		t.Error("envelopes of the second mesh changed")
+	}
+}
+
+/* 30 bones with no subsets, triangle i is weighted to bone i and shares a vert with the next */
+func wideSkeleton() *mesh.Mesh4 {
+	const bones = 30
+	wide := &mesh.Mesh4{}
+	for b := 0; b < bones; b++ {
+		name := fmt.Sprintf("Bone%d", b)
+		wide.Bones = append(wide.Bones, mesh.Bone{BoneNameIndex: uint32(len(wide.NameTable)), ParentIndex: mesh.NoBone, LodParentIndex: mesh.NoBone})
+		wide.NameTable = append(wide.NameTable, name...)
+		wide.NameTable = append(wide.NameTable, 0)
+	}
+	for b := 0; b < bones; b++ {
+		for corner := 0; corner < 2; corner++ {
+			wide.Verts = append(wide.Verts, mesh.VertexModern{Px: float32(b), Py: float32(corner)})
+			wide.Envelopes = append(wide.Envelopes, mesh.Envelope{Bones: [4]byte{byte(b)}, Weights: [4]byte{255}})
+		}
+	}
+	for b := uint32(0); b < bones; b++ {
+		wide.Faces = append(wide.Faces, mesh.Face{A: b * 2, B: b*2 + 1, C: (b*2 + 2) % (bones * 2)})
+	}
+	wide.Lods = []uint32{0, bones}
+	wide.Header = mesh.MeshHeader4{
+		SizeOf_MeshHeader:        mesh.Header4Size,
+		NumVerts:                 uint32(len(wide.Verts)),
+		NumFaces:                 uint32(len(wide.Faces)),
+		NumLods:                  2,
+		NumBones:                 bones,
+		SizeOf_bone_names_Buffer: uint32(len(wide.NameTable)),
+	}
+	return wide
+}
+
+func TestMergeUnnamedBones(t *testing.T) {
+	unnamed := func() *mesh.Mesh4 {
+		skinned := wideSkeleton()
+		skinned.NameTable = []byte{0}
+		skinned.Header.SizeOf_bone_names_Buffer = 1
+		for b := range skinned.Bones {
+			skinned.Bones[b].BoneNameIndex = 0
+		}
+		return skinned
+	}
+	first, second := unnamed(), unnamed()
+	merged, err := mesh.Merge(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.Bones) != len(first.Bones)+len(second.Bones) {
+		t.Errorf("expected unnamed bones to stay apart got %d bones", len(merged.Bones))
+	}
+
+	/* Each vert is still bound to a bone of its own */
+	skin := merged.ToModel().Skin
+	bound := map[uint16]bool{}
+	for _, weights := range skin {
+		bound[weights.Bones[0]] = true
+	}
+	if len(bound) != len(merged.Bones) {
+		t.Errorf("expected every bone to be bound got %d", len(bound))
+	}
+}
+
+func TestMergeSplitsWideSkeleton(t *testing.T) {
+	wide := wideSkeleton()
+	merged, err := mesh.Merge(wide)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(merged.MeshSubsets) != 2 {
+		t.Fatalf("got %d subsets", len(merged.MeshSubsets))
+	}
+	if len(merged.Faces) != len(wide.Faces) {
+		t.Errorf("got %d faces", len(merged.Faces))
+	}
+
+	covered := uint32(0)
+	for _, subset := range merged.MeshSubsets {
+		if subset.NumBonesIndicies > mesh.MaxSubsetBones {
+			t.Errorf("subset has %d bones", subset.NumBonesIndicies)
+		}
+		if subset.FacesBegin != covered {
+			t.Errorf("subset starts at face %d, wanted %d", subset.FacesBegin, covered)
+		}
+		covered += subset.FacesLength
+
+		for f := subset.FacesBegin; f < subset.FacesBegin+subset.FacesLength; f++ {
+			original := wide.Faces[f]
+			face := merged.Faces[f]
+			for corner, v := range [3]uint32{face.A, face.B, face.C} {
+				if v < subset.VertsBegin || v >= subset.VertsBegin+subset.VertsLength {
+					t.Fatalf("face %d uses vert %d outside its subset", f, v)
+				}
+				source := [3]uint32{original.A, original.B, original.C}[corner]
+				if merged.Verts[v] != wide.Verts[source] {
+					t.Errorf("face %d corner %d moved", f, corner)
+				}
+				bone := subset.BoneIndicies[merged.Envelopes[v].Bones[0]]
+				if merged.BoneName(int(bone)) != wide.BoneName(int(wide.Envelopes[source].Bones[0])) {
+					t.Errorf("vert %d is weighted to %s", v, merged.BoneName(int(bone)))
+				}
+			}
+		}
+	}
+	if covered != uint32(len(merged.Faces)) {
+		t.Errorf("subsets cover %d of %d faces", covered, len(merged.Faces))
+	}
+	if err := merged.Validate().Err(); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,6 +1,7 @@
 package mesh
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"io"
@@ -17,6 +18,7 @@ const (
 	MeshVersion4_1
 )
 
+/* The Export methods drop whatever the version cant hold, ExportV1Loss and the rest say what went */
 type Mesh interface {
 	ExportV1() *Mesh1
 	ExportV2() Mesh2
@@ -33,6 +35,9 @@ var (
 	Header2Size      = uint16(unsafe.Sizeof(MeshHeader2{}))
 	Header3Size      = uint16(unsafe.Sizeof(MeshHeader3{}))
 	Header4Size      = uint16(unsafe.Sizeof(MeshHeader4{}))
+	EnvelopeSize     = uint8(unsafe.Sizeof(Envelope{}))
+	BoneSize         = uint8(unsafe.Sizeof(Bone{}))
+	MeshSubsetSize   = uint8(unsafe.Sizeof(MeshSubset{}))
 )
 
 var (
@@ -75,6 +80,27 @@ type Face struct {
 	C uint32
 }
 
+/*
+Splits faces into [start,end) per lod level, lods hold the offset of each level
+followed by the total so a mesh without lods is a single level
+*/
+func lodRanges(lods []uint32, numFaces int) [][2]uint32 {
+	total := uint32(numFaces)
+	if len(lods) < 2 {
+		return [][2]uint32{{0, total}}
+	}
+
+	ranges := make([][2]uint32, 0, len(lods)-1)
+	for i := 0; i+1 < len(lods); i++ {
+		start, end := min(lods[i], total), min(lods[i+1], total)
+		if end < start {
+			end = start
+		}
+		ranges = append(ranges, [2]uint32{start, end})
+	}
+	return ranges
+}
+
 func (V *VertexModern) NoColor() VertexNoRgba {
 	return VertexNoRgba{
 		V.Px, V.Py, V.Pz,
@@ -149,70 +175,71 @@ func WriteValues(stream io.Writer, values ...any) error {
 	return nil
 }
 
+/* Reads a byte at a time without an allocation for each one */
+type singleByteReader struct {
+	stream io.Reader
+	single [1]byte
+}
+
+func (R *singleByteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(R.stream, R.single[:]); err != nil {
+		return 0, err
+	}
+	return R.single[0], nil
+}
+
+/* Nothing past the byte asked for is taken from the stream */
+func byteReader(stream io.Reader) io.ByteReader {
+	if reader, ok := stream.(io.ByteReader); ok {
+		return reader
+	}
+	return &singleByteReader{stream: stream}
+}
+
 func ReadLine(stream io.Reader) (string, error) {
-	LineData := []byte{}
+	reader := byteReader(stream)
+	LineData := make([]byte, 0, versionLineSize)
 	for {
-		currentByte := make([]byte, 1)
-		if _, err := stream.Read(currentByte); err != nil {
+		currentByte, err := reader.ReadByte()
+		if err != nil {
 			return "", err
 		}
-		if currentByte[0] == '\n' {
+		if currentByte == '\n' {
 			break
 		}
-		LineData = append(LineData, currentByte[0])
+		LineData = append(LineData, currentByte)
 	}
 	return string(LineData), nil
 }
 
+/*
+Meshes newer than MaxVersion are converted to ExportVersion, anything else is
+passed through. Conversions decode with DefaultLimits since the input is
+usually untrusted.
+*/
 func MeshDecodeLayer(MaxVersion uint8, ExportVersion uint8) func(io.Reader, io.Writer) error {
-	return func(rc io.Reader, wc io.Writer) error {
-		meshVersion, err := MeshVersion(rc)
-		if err != nil {
-			return err
-		}
-		if meshVersion > MaxVersion {
-			parsedMesh, err := decodeMesh(rc, meshVersion)
-			if err != nil {
-				return err
-			}
-			newMesh := EncodeMeshVersion(parsedMesh, ExportVersion)
-			if newMesh == nil {
-				return ErrBadMeshVersion
-			}
-			return newMesh.Write(wc)
+	policy := Policy{Target: ExportVersion, Options: DecodeOptions{Limits: DefaultLimits}}
+	policy.Supported, policy.SupportedVersions = versionsUpTo(MaxVersion)
+	if policy.Supported == 0 && len(policy.SupportedVersions) == 0 {
+		/* MaxVersion isnt a version so only ExportVersion itself can go straight through */
+		if builtinVersion(ExportVersion) {
+			policy.Supported = ExportVersion
 		} else {
-			meshHeader, err := MeshHeader(meshVersion)
-			if err != nil {
-				return err
-			}
-			if _, err := wc.Write([]byte(meshHeader + "\n")); err != nil {
-				return err
-			}
-			_, err = io.Copy(wc, rc)
-			return err
+			policy.SupportedVersions = []uint8{ExportVersion}
 		}
-
+	}
+	return func(rc io.Reader, wc io.Writer) error {
+		_, err := policy.Convert(rc, wc)
+		return err
 	}
 }
+
 func MeshHeader(meshVersion uint8) (string, error) {
-	switch meshVersion {
-	case MeshVersion1:
-		return "version 1.00", nil
-	case MeshVersion1_01:
-		return "version 1.01", nil
-	case MeshVersion2:
-		return "version 2.00", nil
-	case MeshVersion3:
-		return "version 3.00", nil
-	case MeshVersion3_01:
-		return "version 3.01", nil
-	case MeshVersion4:
-		return "version 4.00", nil
-	case MeshVersion4_1:
-		return "version 4.01", nil
-	default:
+	codec, ok := codecForVersion(meshVersion)
+	if !ok {
 		return "", ErrUnkownMeshVersion
 	}
+	return codec.VersionLine, nil
 }
 
 func MeshVersion(stream io.Reader) (uint8, error) {
@@ -221,63 +248,68 @@ func MeshVersion(stream io.Reader) (uint8, error) {
 		return 0, err
 	}
 
-	switch meshVersion {
-	case "version 1.00":
-		return MeshVersion1, nil
-	case "version 1.01":
-		return MeshVersion1_01, nil
-	case "version 2.00":
-		return MeshVersion2, nil
-	case "version 3.00":
-		return MeshVersion3, nil
-	case "version 3.01":
-		return MeshVersion3_01, nil
-	case "version 4.00":
-		return MeshVersion4, nil
-	case "version 4.01":
-		return MeshVersion4_1, nil
-	default:
+	codec, ok := findCodec(func(C *Codec) bool { return C.VersionLine != "" && C.VersionLine == meshVersion })
+	if !ok {
 		return 0, ErrUnkownMeshVersion
 	}
-
+	return codec.Version, nil
 }
 
 func DecodeMesh(stream io.Reader) (Mesh, error) {
-	version, err := MeshVersion(stream)
-	if err != nil {
-		return nil, err
-	}
-
-	return decodeMesh(stream, version)
+	return DecodeMeshOptions(stream, DecodeOptions{Limits: DefaultLimits})
 }
 
-func decodeMesh(stream io.Reader, version uint8) (Mesh, error) {
-	switch version {
-	case MeshVersion1, MeshVersion1_01:
-		return nil, ErrMeshVersion1
-	case MeshVersion2:
-		stream2 := MeshStream2{stream}
-		return stream2.LoadMesh()
-	case MeshVersion3, MeshVersion3_01:
-		stream3 := MeshStream3{stream}
-		return stream3.LoadMesh()
-	case MeshVersion4, MeshVersion4_1:
-		stream4 := MeshStream4{stream}
-		return stream4.LoadMesh()
-	default:
+/* The version line was already read, the codec for version takes the rest */
+func decodeMeshOptions(stream io.Reader, version uint8, opts DecodeOptions) (Mesh, error) {
+	codec, ok := codecForVersion(version)
+	if !ok || codec.Decode == nil {
 		return nil, ErrUnkownMeshVersion
 	}
+	return codec.Decode(stream, opts)
 }
 
+/*
+Converts mesh into the type version decodes to, nil when it cant. Registered
+versions with their own layout go through their codec and back.
+*/
 func EncodeMeshVersion(mesh Mesh, version uint8) Mesh {
-	switch version {
+	codec, ok := codecForVersion(version)
+	if !ok || codec.Encode == nil {
+		return nil
+	}
+	switch versionFamily(version) {
+	case MeshVersion1:
+		return nil
 	case MeshVersion2:
-		return mesh.ExportV2()
-	case MeshVersion3, MeshVersion3_01:
-		return mesh.ExportV3()
-	case MeshVersion4, MeshVersion4_1:
-		return mesh.ExportV4()
-	default:
+		if mesh2, err := exportV2(mesh); err == nil {
+			return mesh2
+		}
+		return nil
+	case MeshVersion3:
+		if mesh3, err := exportV3(mesh); err == nil {
+			return mesh3
+		}
+		return nil
+	case MeshVersion4:
+		if mesh4, err := exportV4(mesh); err == nil {
+			return mesh4
+		}
+		return nil
+	}
+
+	if codec.Decode == nil {
+		return nil
+	}
+	buffer := bytes.Buffer{}
+	if err := codec.Encode(&buffer, mesh); err != nil {
+		return nil
+	}
+	if _, err := MeshVersion(&buffer); err != nil {
+		return nil
+	}
+	decoded, err := codec.Decode(&buffer, DecodeOptions{})
+	if err != nil {
 		return nil
 	}
+	return decoded
 }
@@ -1,6 +1,7 @@
 package mesh
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"io"
@@ -96,7 +97,9 @@ func (M *Mesh1) GetAllVerticies(faces []Face) []VertexV1 {
 	return vertBuffer
 }
 
-func (V *Mesh1) Write(output io.Writer) error {
+func (V *Mesh1) Write(stream io.Writer) error {
+	/* Every vertex is a handful of tiny writes */
+	output := bufio.NewWriter(stream)
 	headerOutput := fmt.Sprintf("version 1.01\n%d\n", len(V.Verts)/3)
 	if _, err := output.Write([]byte(headerOutput)); err != nil {
 		return err
@@ -106,7 +109,7 @@ func (V *Mesh1) Write(output io.Writer) error {
 			return err
 		}
 	}
-	return nil
+	return output.Flush()
 }
 
 func (V *Mesh1) NoColorVerts() []VertexNoRgba {
@@ -167,18 +170,19 @@ func (M *Mesh1) ExportV4() *Mesh4 {
 */
 
 func (S *MeshStream1) ReadNumber() (float32, error) {
-	numberBytes := []byte{}
+	reader := byteReader(S.Stream)
+	numberBytes := make([]byte, 0, 16)
 	for {
-		currentByte := make([]byte, 1)
-		if _, err := S.Stream.Read(currentByte); err != nil {
+		currentByte, err := reader.ReadByte()
+		if err != nil {
 			return 0, err
 		}
-		if currentByte[0] == ']' || currentByte[0] == ',' {
+		if currentByte == ']' || currentByte == ',' {
 			break
-		} else if currentByte[0] == '[' {
+		} else if currentByte == '[' {
 			return 0, ErrUnexpectedBracket
 		}
-		numberBytes = append(numberBytes, currentByte[0])
+		numberBytes = append(numberBytes, currentByte)
 	}
 	if float, err := strconv.ParseFloat(string(numberBytes), 32); err == nil {
 		return float32(float), nil
@@ -188,26 +192,14 @@ func (S *MeshStream1) ReadNumber() (float32, error) {
 }
 
 func (S *MeshStream1) ReadLine() (string, error) {
-	LineData := []byte{}
-	for {
-		currentByte := make([]byte, 1)
-		if _, err := S.Stream.Read(currentByte); err != nil {
-			return "", err
-		}
-		if currentByte[0] == '\n' {
-			break
-		}
-		LineData = append(LineData, currentByte[0])
-	}
-	return string(LineData), nil
+	return ReadLine(S.Stream)
 }
 
 func (S *MeshStream1) ReadVector3() (*Vector3, error) {
-	newData := make([]byte, 1)
-	if _, err := S.Stream.Read(newData); err != nil {
+	if currentByte, err := byteReader(S.Stream).ReadByte(); err != nil {
 		return nil, err
-	} else if newData[0] != '[' {
-		return nil, errors.Join(ErrInvalidOffset, fmt.Errorf("illegal character %s", string(newData)))
+	} else if currentByte != '[' {
+		return nil, errors.Join(ErrInvalidOffset, fmt.Errorf("illegal character %s", string(currentByte)))
 	}
 
 	X, err := S.ReadNumber()
@@ -251,23 +243,41 @@ func (S *MeshStream1) ReadVertex() (*VertexV1, error) {
 
 /* This shit is so slow */
 func (S *MeshStream1) LoadMesh() (*Mesh1, error) {
+	return S.LoadMeshOptions(DecodeOptions{Limits: DefaultLimits})
+}
+
+func (S *MeshStream1) LoadMeshOptions(opts DecodeOptions) (*Mesh1, error) {
+	reader := newMeshReader(S.Stream, opts, MeshVersion1_01)
+	S = &MeshStream1{reader}
+
+	start := reader.position()
 	FaceCountRaw, err := S.ReadLine()
 	if err != nil {
-		return nil, err
+		return nil, reader.fail(SectionHeader, -1, start, err)
 	}
 	FaceCountI, err := strconv.Atoi(FaceCountRaw)
 	if err != nil {
-		return nil, err
+		return nil, reader.fail(SectionHeader, -1, start, err)
+	}
+	if err := reader.checkFaceCount1(FaceCountI); err != nil {
+		return nil, reader.fail(SectionHeader, -1, start, err)
 	}
 	FaceCount := uint32(FaceCountI)
-	Verts := make([]VertexV1, FaceCount*3)
-	for i := uint32(0); i < FaceCount*3; i++ {
+	Verts := make([]VertexV1, reader.capped(uint64(FaceCount)*3, minVertexV1Size))
+	for i := range Verts {
+		start := reader.position()
 		vert, err := S.ReadVertex()
 		if err != nil {
-			return nil, err
+			err = reader.fail(SectionVertices, i, start, err)
+			if !opts.Lenient || !truncation(err) {
+				return nil, err
+			}
+			Verts, reader.truncated = Verts[:i], err
+			break
 		}
 		Verts[i] = *vert
 	}
 
-	return &Mesh1{FaceCount, Verts}, nil
+	newMesh := Mesh1{FaceCount, Verts}
+	return &newMesh, reader.salvage1(&newMesh)
 }
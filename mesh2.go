@@ -43,59 +43,59 @@ func (S *MeshStream2) ReadValue(ptr any) error {
 	return binary.Read(S.Stream, binary.LittleEndian, ptr)
 }
 
-func (S *MeshStream2) loadMeshNoRgba(header MeshHeader2) (*Mesh2NoRgba, error) {
-	newMesh := Mesh2NoRgba{
-		Header: header,
-		Verts:  make([]VertexNoRgba, header.NumVerts),
-		Faces:  make([]Face, header.NumFaces),
-	}
+func (S *MeshStream2) loadMeshNoRgba(reader *meshReader, header MeshHeader2) (*Mesh2NoRgba, error) {
+	newMesh := Mesh2NoRgba{Header: header}
 
-	for i := uint32(0); i < header.NumVerts; i++ {
-		if err := S.ReadValue(&newMesh.Verts[i]); err != nil {
-			return nil, err
-		}
+	if err := readSection(reader, SectionVertices, &newMesh.Verts, uint64(header.NumVerts)); err != nil {
+		return nil, err
 	}
-	for i := uint32(0); i < header.NumFaces; i++ {
-		if err := S.ReadValue(&newMesh.Faces[i]); err != nil {
-			return nil, err
-		}
+	if err := readSection(reader, SectionFaces, &newMesh.Faces, uint64(header.NumFaces)); err != nil {
+		return nil, err
 	}
 
-	return &newMesh, nil
+	return &newMesh, reader.salvage2(&newMesh.Header, len(newMesh.Verts), &newMesh.Faces)
 }
-func (S *MeshStream2) loadMeshRgba(header MeshHeader2) (*Mesh2Rgba, error) {
-	newMesh := Mesh2Rgba{
-		Header: header,
-		Verts:  make([]VertexModern, header.NumVerts),
-		Faces:  make([]Face, header.NumFaces),
-	}
+func (S *MeshStream2) loadMeshRgba(reader *meshReader, header MeshHeader2) (*Mesh2Rgba, error) {
+	newMesh := Mesh2Rgba{Header: header}
 
-	for i := uint32(0); i < header.NumVerts; i++ {
-		if err := S.ReadValue(&newMesh.Verts[i]); err != nil {
-			return nil, err
-		}
+	if err := readSection(reader, SectionVertices, &newMesh.Verts, uint64(header.NumVerts)); err != nil {
+		return nil, err
 	}
-	for i := uint32(0); i < header.NumFaces; i++ {
-		if err := S.ReadValue(&newMesh.Faces[i]); err != nil {
-			return nil, err
-		}
+	if err := readSection(reader, SectionFaces, &newMesh.Faces, uint64(header.NumFaces)); err != nil {
+		return nil, err
 	}
 
-	return &newMesh, nil
+	return &newMesh, reader.salvage2(&newMesh.Header, len(newMesh.Verts), &newMesh.Faces)
 }
 
 func (S *MeshStream2) LoadMesh() (Mesh2, error) {
-	header, err := S.ReadHeader()
-	if err != nil {
+	return S.LoadMeshOptions(DecodeOptions{Limits: DefaultLimits})
+}
+
+func (S *MeshStream2) LoadMeshOptions(opts DecodeOptions) (Mesh2, error) {
+	reader := newMeshReader(S.Stream, opts, MeshVersion2)
+
+	header := MeshHeader2{}
+	if err := reader.readHeader(&header); err != nil {
 		return nil, err
 	}
+	if err := reader.checkHeader2(&header); err != nil {
+		return nil, reader.fail(SectionHeader, -1, reader.position(), err)
+	}
 
+	/* Partial meshes come back with their error */
 	switch header.VertexSize {
 	case 36:
-		mesh2, err := S.loadMeshNoRgba(*header)
+		mesh2, err := S.loadMeshNoRgba(reader, header)
+		if mesh2 == nil {
+			return nil, err
+		}
 		return mesh2, err
 	default:
-		mesh2, err := S.loadMeshRgba(*header)
+		mesh2, err := S.loadMeshRgba(reader, header)
+		if mesh2 == nil {
+			return nil, err
+		}
 		return mesh2, err
 	}
 }
@@ -114,7 +114,8 @@ func (M *Mesh2NoRgba) GetAllVerticies(faces []Face) []Vertex {
 
 func (S *Mesh2NoRgba) ExportV1() *Mesh1 {
 	mesh1 := Mesh1{
-		Verts: make([]VertexV1, len(S.Faces)*3),
+		FaceCount: uint32(len(S.Faces)),
+		Verts:     make([]VertexV1, len(S.Faces)*3),
 	}
 	for i, vertex := range S.GetAllVerticies(S.Faces) {
 		mesh1.Verts[i] = vertex.Legacy()
@@ -134,7 +135,7 @@ func (S *Mesh2NoRgba) ExportV3() *Mesh3 {
 		FaceSize:       uint8(FaceSize),
 		SizeofLod:      uint16(unsafe.Sizeof(uint32(0))),
 
-		NumLods:  0,
+		NumLods:  uint16(len(singleLod(uint32(len(S.Faces))))),
 		NumVerts: S.Header.NumVerts,
 		NumFaces: S.Header.NumFaces,
 	}
@@ -142,7 +143,7 @@ func (S *Mesh2NoRgba) ExportV3() *Mesh3 {
 		Header: newHeader,
 		Verts:  S.ConvertVerts(),
 		Faces:  S.Faces,
-		Lods:   make([]uint32, 0),
+		Lods:   singleLod(uint32(len(S.Faces))),
 	}
 	return &newMesh
 }
@@ -172,8 +173,8 @@ func (M *Mesh2Rgba) GetAllVerticies(faces []Face) []Vertex {
 
 func (S *Mesh2Rgba) ExportV1() *Mesh1 {
 	mesh1 := Mesh1{
-		FaceCount: S.Header.NumFaces,
-		Verts:     make([]VertexV1, S.Header.NumFaces),
+		FaceCount: uint32(len(S.Faces)),
+		Verts:     make([]VertexV1, len(S.Faces)*3),
 	}
 	for i, vertex := range S.GetAllVerticies(S.Faces) {
 		mesh1.Verts[i] = vertex.Legacy()
@@ -192,7 +193,7 @@ func (S *Mesh2Rgba) ExportV3() *Mesh3 {
 		FaceSize:       uint8(FaceSize),
 		SizeofLod:      uint16(unsafe.Sizeof(uint32(0))),
 
-		NumLods:  2,
+		NumLods:  uint16(len(singleLod(uint32(len(S.Faces))))),
 		NumVerts: S.Header.NumVerts,
 		NumFaces: S.Header.NumFaces,
 	}
@@ -200,7 +201,7 @@ func (S *Mesh2Rgba) ExportV3() *Mesh3 {
 		Header: newHeader,
 		Verts:  S.Verts,
 		Faces:  S.Faces,
-		Lods:   []uint32{0, uint32(len(S.Faces))},
+		Lods:   singleLod(uint32(len(S.Faces))),
 	}
 	return &newMesh
 }
@@ -217,17 +218,10 @@ func (M *Mesh2Rgba) Write(stream io.Writer) error {
 		return err
 	}
 
-	for _, vertex := range M.Verts {
-		if err := binary.Write(stream, binary.LittleEndian, vertex); err != nil {
-			return err
-		}
-	}
-	for _, face := range M.Faces {
-		if err := binary.Write(stream, binary.LittleEndian, face); err != nil {
-			return err
-		}
+	if err := writeSection(stream, M.Verts, len(M.Verts)); err != nil {
+		return err
 	}
-	return nil
+	return writeSection(stream, M.Faces, len(M.Faces))
 }
 
 func (M *Mesh2NoRgba) Write(stream io.Writer) error {
@@ -238,15 +232,8 @@ func (M *Mesh2NoRgba) Write(stream io.Writer) error {
 		return err
 	}
 
-	for _, vertex := range M.Verts {
-		if err := binary.Write(stream, binary.LittleEndian, vertex); err != nil {
-			return err
-		}
-	}
-	for _, face := range M.Faces {
-		if err := binary.Write(stream, binary.LittleEndian, face); err != nil {
-			return err
-		}
+	if err := writeSection(stream, M.Verts, len(M.Verts)); err != nil {
+		return err
 	}
-	return nil
+	return writeSection(stream, M.Faces, len(M.Faces))
 }
@@ -3,6 +3,7 @@ package mesh_test
 import (
 	"github.com/MojaveMF/mesh"
 	"os"
+	"slices"
 	"testing"
 )
 
@@ -89,3 +90,37 @@ func TestConvertV2_V4(t *testing.T) {
 	meshData.ExportV4().Write(output)
 
 }
+
+func TestExportV3Lods(t *testing.T) {
+	rgba := twoTriangles().ExportV2().(*mesh.Mesh2Rgba)
+	noRgba := &mesh.Mesh2NoRgba{Header: rgba.Header, Faces: rgba.Faces}
+	for _, vertex := range rgba.Verts {
+		noRgba.Verts = append(noRgba.Verts, vertex.NoColor())
+	}
+
+	/* A stale header should not push the lod past the faces */
+	rgba.Header.NumFaces, noRgba.Header.NumFaces = 10, 10
+	for _, v2 := range []mesh.Mesh2{rgba, noRgba} {
+		mesh3 := v2.ExportV3()
+		if !slices.Equal(mesh3.Lods, []uint32{0, 2}) || mesh3.Header.NumLods != 2 {
+			t.Errorf("%T: expected a single lod over 2 faces got %v", v2, mesh3.Lods)
+		}
+	}
+}
+
+func TestExportV1FaceCount(t *testing.T) {
+	rgba := twoTriangles().ExportV2().(*mesh.Mesh2Rgba)
+	noRgba := &mesh.Mesh2NoRgba{Header: rgba.Header, Faces: rgba.Faces}
+	for _, vertex := range rgba.Verts {
+		noRgba.Verts = append(noRgba.Verts, vertex.NoColor())
+	}
+
+	/* Every face gets three verts whatever the header says */
+	rgba.Header.NumFaces, noRgba.Header.NumFaces = 10, 10
+	for _, v2 := range []mesh.Mesh2{rgba, noRgba} {
+		mesh1 := v2.ExportV1()
+		if mesh1.FaceCount != 2 || len(mesh1.Verts) != 6 {
+			t.Errorf("%T: expected 2 faces and 6 verts got %d and %d", v2, mesh1.FaceCount, len(mesh1.Verts))
+		}
+	}
+}
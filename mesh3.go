@@ -40,67 +40,58 @@ func (S *MeshStream3) ReadValue(ptr any) error {
 }
 
 func (S *MeshStream3) LoadMesh() (*Mesh3, error) {
-	header, err := S.ReadHeader()
-	if err != nil {
+	return S.LoadMeshOptions(DecodeOptions{Limits: DefaultLimits})
+}
+
+func (S *MeshStream3) LoadMeshOptions(opts DecodeOptions) (*Mesh3, error) {
+	reader := newMeshReader(S.Stream, opts, MeshVersion3)
+
+	header := MeshHeader3{}
+	if err := reader.readHeader(&header); err != nil {
 		return nil, err
 	}
-
-	newMesh := Mesh3{
-		Header: *header,
-		Verts:  make([]VertexModern, header.NumVerts),
-		Faces:  make([]Face, header.NumFaces),
-		Lods:   make([]uint32, header.NumLods),
+	if err := reader.checkHeader3(&header); err != nil {
+		return nil, reader.fail(SectionHeader, -1, reader.position(), err)
 	}
 
-	for i := uint32(0); i < header.NumVerts; i++ {
-		if err := S.ReadValue(&newMesh.Verts[i]); err != nil {
-			return nil, err
-		}
-	}
+	newMesh := Mesh3{Header: header}
 
-	for i := uint32(0); i < header.NumFaces; i++ {
-		if err := S.ReadValue(&newMesh.Faces[i]); err != nil {
-			return nil, err
-		}
+	if err := readSection(reader, SectionVertices, &newMesh.Verts, uint64(header.NumVerts)); err != nil {
+		return nil, err
 	}
-
-	for i := uint16(0); i < header.NumLods; i++ {
-		if err := S.ReadValue(&newMesh.Lods[i]); err != nil {
-			return nil, err
-		}
+	if err := readSection(reader, SectionFaces, &newMesh.Faces, uint64(header.NumFaces)); err != nil {
+		return nil, err
 	}
-	return &newMesh, nil
+	if err := readSection(reader, SectionLods, &newMesh.Lods, uint64(header.NumLods)); err != nil {
+		return nil, err
+	}
+	return &newMesh, reader.salvage3(&newMesh)
 }
 
 func (M *Mesh3) Write(stream io.Writer) error {
+	return M.writeVersion(stream, "version 3.00\n")
+}
+
+/* 3.01 is written the same way with its own version line */
+func (M *Mesh3) writeVersion(stream io.Writer, versionLine string) error {
 	/* Write metadata bs */
-	if _, err := stream.Write([]byte("version 3.00\n")); err != nil {
+	if _, err := io.WriteString(stream, versionLine); err != nil {
 		return err
 	} else if err := binary.Write(stream, binary.LittleEndian, M.Header); err != nil {
 		return err
 	}
 
-	for _, vertex := range M.Verts {
-		if err := binary.Write(stream, binary.LittleEndian, vertex); err != nil {
-			return err
-		}
-	}
-	for _, face := range M.Faces {
-		if err := binary.Write(stream, binary.LittleEndian, face); err != nil {
-			return err
-		}
+	if err := writeSection(stream, M.Verts, len(M.Verts)); err != nil {
+		return err
 	}
-	for _, lodData := range M.Lods {
-		if err := binary.Write(stream, binary.LittleEndian, lodData); err != nil {
-			return err
-		}
+	if err := writeSection(stream, M.Faces, len(M.Faces)); err != nil {
+		return err
 	}
-
-	return nil
+	return writeSection(stream, M.Lods, len(M.Lods))
 }
 
 func (M *Mesh3) GetNormalFaces() []Face {
-	if len(M.Lods) > 1 {
+	if len(M.Lods) > 1 && M.Lods[1] <= uint32(len(M.Faces)) {
 		return M.Faces[:M.Lods[1]]
 	}
 	return M.Faces
@@ -2,12 +2,19 @@ package mesh
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
 	"unsafe"
 )
 
 type ushort = uint16
 
+/* Used by ParentIndex and BoneIndicies for "no bone" */
+const NoBone ushort = 0xFFFF
+
+/* Roblox only lets a single subset reference this many bones */
+const MaxSubsetBones = 26
+
 type Mesh4 struct {
 	Header      MeshHeader4
 	Verts       []VertexModern
@@ -84,69 +91,53 @@ func (S *MeshStream4) ReadValue(ptr any) error {
 }
 
 func (S *MeshStream4) LoadMesh() (*Mesh4, error) {
-	header, err := S.ReadHeader()
-	if err != nil {
+	return S.LoadMeshOptions(DecodeOptions{Limits: DefaultLimits})
+}
+
+func (S *MeshStream4) LoadMeshOptions(opts DecodeOptions) (*Mesh4, error) {
+	reader := newMeshReader(S.Stream, opts, MeshVersion4)
+
+	header := MeshHeader4{}
+	if err := reader.readHeader(&header); err != nil {
 		return nil, err
 	}
-
-	newMesh := Mesh4{
-		Header:      *header,
-		Verts:       make([]VertexModern, header.NumVerts),
-		Envelopes:   make([]Envelope, header.NumVerts),
-		Faces:       make([]Face, header.NumFaces),
-		Lods:        make([]uint32, header.NumLods),
-		Bones:       make([]Bone, header.NumBones),
-		NameTable:   make([]byte, header.SizeOf_bone_names_Buffer),
-		MeshSubsets: make([]MeshSubset, header.NumSubsets),
+	if err := reader.checkHeader4(&header); err != nil {
+		return nil, reader.fail(SectionHeader, -1, reader.position(), err)
 	}
 
-	for i := uint32(0); i < header.NumVerts; i++ {
-		if err := S.ReadValue(&newMesh.Verts[i]); err != nil {
-			return nil, err
-		}
+	newMesh := Mesh4{Header: header}
+
+	if err := readSection(reader, SectionVertices, &newMesh.Verts, uint64(header.NumVerts)); err != nil {
+		return nil, err
 	}
 	if header.NumBones > 0 {
-		for i := uint32(0); i < header.NumVerts; i++ {
-			if err := S.ReadValue(&newMesh.Envelopes[i]); err != nil {
-				return nil, err
-			}
-		}
-	}
-
-	for i := uint32(0); i < header.NumFaces; i++ {
-		if err := S.ReadValue(&newMesh.Faces[i]); err != nil {
+		if err := readSection(reader, SectionEnvelopes, &newMesh.Envelopes, uint64(header.NumVerts)); err != nil {
 			return nil, err
 		}
+	} else {
+		newMesh.Envelopes = make([]Envelope, len(newMesh.Verts))
 	}
-
-	for i := uint16(0); i < header.NumLods; i++ {
-		if err := S.ReadValue(&newMesh.Lods[i]); err != nil {
-			return nil, err
-		}
+	if err := readSection(reader, SectionFaces, &newMesh.Faces, uint64(header.NumFaces)); err != nil {
+		return nil, err
 	}
-
-	for i := uint16(0); i < header.NumBones; i++ {
-		if err := S.ReadValue(&newMesh.Bones[i]); err != nil {
-			return nil, err
-		}
-
+	if err := readSection(reader, SectionLods, &newMesh.Lods, uint64(header.NumLods)); err != nil {
+		return nil, err
 	}
-
-	if _, err := S.Stream.Read(newMesh.NameTable); err != nil {
-
+	if err := readSection(reader, SectionBones, &newMesh.Bones, uint64(header.NumBones)); err != nil {
+		return nil, err
 	}
-
-	for i := uint16(0); i < header.NumSubsets; i++ {
-		if err := S.ReadValue(&newMesh.MeshSubsets[i]); err != nil {
-			return nil, err
-		}
+	if err := readSection(reader, SectionNames, &newMesh.NameTable, uint64(header.SizeOf_bone_names_Buffer)); err != nil {
+		return nil, err
+	}
+	if err := readSection(reader, SectionSubsets, &newMesh.MeshSubsets, uint64(header.NumSubsets)); err != nil {
+		return nil, err
 	}
 
-	return &newMesh, nil
+	return &newMesh, reader.salvage4(&newMesh)
 }
 
 func (M *Mesh4) GetNormalFaces() []Face {
-	if len(M.Lods) > 0 {
+	if len(M.Lods) > 1 && M.Lods[1] <= uint32(len(M.Faces)) {
 		return M.Faces[:M.Lods[1]]
 	}
 	return M.Faces
@@ -164,56 +155,89 @@ func (M *Mesh4) GetAllVerticies(faces []Face) []VertexModern {
 	return vertBuffer
 }
 
-func (M *Mesh4) Write(stream io.Writer) error {
-	if _, err := stream.Write([]byte("version 4.00\n")); err != nil {
-		return err
-	} else if err := binary.Write(stream, binary.LittleEndian, M.Header); err != nil {
-		return err
+/* Bone names are null terminated strings packed into the name table */
+func (M *Mesh4) BoneName(index int) string {
+	if index < 0 || index >= len(M.Bones) {
+		return ""
 	}
-
-	for i := uint32(0); i < M.Header.NumVerts; i++ {
-		if err := binary.Write(stream, binary.LittleEndian, M.Verts[i]); err != nil {
-			return err
-		}
+	start := M.Bones[index].BoneNameIndex
+	if start >= uint32(len(M.NameTable)) {
+		return ""
 	}
-	if M.Header.NumBones > 0 {
-		for i := uint32(0); i < M.Header.NumVerts; i++ {
-			if err := binary.Write(stream, binary.LittleEndian, M.Envelopes[i]); err != nil {
-				return err
-			}
+	name := M.NameTable[start:]
+	for i, char := range name {
+		if char == 0 {
+			return string(name[:i])
 		}
 	}
+	return string(name)
+}
 
-	for i := uint32(0); i < M.Header.NumFaces; i++ {
-		if err := binary.Write(stream, binary.LittleEndian, M.Faces[i]); err != nil {
-			return err
-		}
-	}
+func (M *Mesh4) Write(stream io.Writer) error {
+	return M.writeVersion(stream, "version 4.00\n")
+}
 
-	for i := uint16(0); i < M.Header.NumLods; i++ {
-		if err := binary.Write(stream, binary.LittleEndian, M.Lods[i]); err != nil {
-			return err
-		}
+/* Checked before anything is written so a bad header never leaves half a file */
+func (M *Mesh4) checkCounts() error {
+	header := M.Header
+	counts := []struct {
+		section         Section
+		declared, items int
+	}{
+		{SectionVertices, int(header.NumVerts), len(M.Verts)},
+		{SectionEnvelopes, int(header.NumVerts), len(M.Envelopes)},
+		{SectionFaces, int(header.NumFaces), len(M.Faces)},
+		{SectionLods, int(header.NumLods), len(M.Lods)},
+		{SectionBones, int(header.NumBones), len(M.Bones)},
+		{SectionNames, int(header.SizeOf_bone_names_Buffer), len(M.NameTable)},
+		{SectionSubsets, int(header.NumSubsets), len(M.MeshSubsets)},
 	}
-
-	if M.Header.NumBones > 0 {
-		for i := uint16(0); i < M.Header.NumBones; i++ {
-			if err := binary.Write(stream, binary.LittleEndian, M.Bones[i]); err != nil {
-				return err
-			}
+	for _, count := range counts {
+		/* Envelopes are only written for skinned meshes */
+		if count.section == SectionEnvelopes && header.NumBones == 0 {
+			continue
 		}
-		if err := binary.Write(stream, binary.LittleEndian, M.NameTable); err != nil {
-			return err
+		if count.declared > count.items {
+			return fmt.Errorf("%s: header says %d but there are %d: %w", count.section, count.declared, count.items, ErrCountMismatch)
 		}
 	}
+	return nil
+}
 
-	for i := uint16(0); i < M.Header.NumSubsets; i++ {
-		if err := binary.Write(stream, binary.LittleEndian, M.MeshSubsets[i]); err != nil {
+/* 4.01 only changes the version line */
+func (M *Mesh4) writeVersion(stream io.Writer, versionLine string) error {
+	if err := M.checkCounts(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(stream, versionLine); err != nil {
+		return err
+	} else if err := binary.Write(stream, binary.LittleEndian, M.Header); err != nil {
+		return err
+	}
+
+	header := M.Header
+	if err := writeSection(stream, M.Verts, int(header.NumVerts)); err != nil {
+		return err
+	}
+	if header.NumBones > 0 {
+		if err := writeSection(stream, M.Envelopes, int(header.NumVerts)); err != nil {
 			return err
 		}
 	}
-
-	return nil
+	if err := writeSection(stream, M.Faces, int(header.NumFaces)); err != nil {
+		return err
+	}
+	if err := writeSection(stream, M.Lods, int(header.NumLods)); err != nil {
+		return err
+	}
+	if err := writeSection(stream, M.Bones, int(header.NumBones)); err != nil {
+		return err
+	}
+	/* The reader always takes SizeOf_bone_names_Buffer bytes, bones or not */
+	if err := writeSection(stream, M.NameTable, int(header.SizeOf_bone_names_Buffer)); err != nil {
+		return err
+	}
+	return writeSection(stream, M.MeshSubsets, int(header.NumSubsets))
 }
 
 /* Using mesh v1.01 since i dont feel like descaling */
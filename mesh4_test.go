@@ -1,6 +1,8 @@
 package mesh_test
 
 import (
+	"bytes"
+	"errors"
 	"github.com/MojaveMF/mesh"
 	"os"
 	"testing"
@@ -86,3 +88,16 @@ func TestConvertV4_V4(t *testing.T) {
 
 	meshData.ExportV4().Write(output)
 }
+
+func TestWriteCountMismatch(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+	mesh4.Header.SizeOf_bone_names_Buffer = uint32(len(mesh4.NameTable)) + 100
+
+	buffer := bytes.Buffer{}
+	if err := mesh4.Write(&buffer); !errors.Is(err, mesh.ErrCountMismatch) {
+		t.Errorf("expected a count mismatch got %v", err)
+	}
+	if buffer.Len() != 0 {
+		t.Errorf("wrote %d bytes of a mesh that cant be written", buffer.Len())
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package mesh
+
+import (
+	"errors"
+	"os"
+)
+
+/* OpenFile reads the file into an aligned buffer instead */
+func mapFile(file *os.File) ([]byte, func([]byte) error, error) {
+	return nil, nil, errors.New("mapping files is not supported here")
+}
@@ -0,0 +1,30 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package mesh
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+/*
+Maps the file copy on write so edits through the mesh never reach the disk.
+Mappings start on a page which leaves verts and faces unaligned, they are
+only read in place where the architecture allows it.
+*/
+func mapFile(file *os.File) ([]byte, func([]byte) error, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := info.Size()
+	if size <= 0 || int64(int(size)) != size {
+		return nil, nil, errors.New("file cant be mapped")
+	}
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, syscall.Munmap, nil
+}
@@ -0,0 +1,480 @@
+package mesh
+
+import (
+	"cmp"
+	"io"
+	"slices"
+)
+
+/* Attributes a mesh actually stored, anything missing is filled with the same defaults the exports use */
+type Attributes uint16
+
+const (
+	AttributeNormals Attributes = 1 << iota
+	AttributeUVs
+	AttributeTangents
+	AttributeColors
+	AttributeLods
+	AttributeSkinning
+	AttributeSubsets
+)
+
+func (A Attributes) Has(attributes Attributes) bool {
+	return A&attributes == attributes
+}
+
+type UV struct {
+	U, V float32
+}
+
+/* Components are scaled to -127..127, S is the bitangent sign */
+type Tangent struct {
+	X, Y, Z, S int8
+}
+
+type Color struct {
+	R, G, B, A byte
+}
+
+type ModelBone struct {
+	Name      string
+	Parent    uint16 /* NoBone for roots */
+	LodParent uint16
+	Culling   float32
+	Rotation  [9]float32 /* row major, same order as R00..R22 */
+	Position  Vector3
+}
+
+/* Bones index Model.Bones, unlike envelopes in a file which index the subset */
+type SkinWeights struct {
+	Bones   [4]uint16
+	Weights [4]byte
+}
+
+type ModelSubset struct {
+	FacesBegin  uint32
+	FacesLength uint32
+	VertsBegin  uint32
+	VertsLength uint32
+	Bones       []uint16 /* indices into Model.Bones */
+}
+
+/*
+One shape for every mesh version. Every vertex attribute slice is as long as
+Positions, Skin is empty unless the mesh is skinned. Lods are face offsets
+the same as in Mesh3 and Mesh4.
+*/
+type Model struct {
+	Version uint8 /* version it came from, 0 when built by hand */
+	Present Attributes
+
+	Positions []Vector3
+	Normals   []Vector3
+	UVs       []UV
+	Tangents  []Tangent
+	Colors    []Color
+
+	Faces []Face
+	Lods  []uint32
+
+	Bones   []ModelBone
+	Skin    []SkinWeights
+	Subsets []ModelSubset
+
+	/* v4 header fields with nowhere else to go */
+	LodType         uint16
+	HighQualityLods uint8
+}
+
+func (M *Model) addVertex(vertex VertexModern) {
+	M.Positions = append(M.Positions, Vector3{vertex.Px, vertex.Py, vertex.Pz})
+	M.Normals = append(M.Normals, Vector3{vertex.Nx, vertex.Ny, vertex.Nz})
+	M.UVs = append(M.UVs, UV{vertex.Tu, vertex.Tv})
+	M.Tangents = append(M.Tangents, Tangent{vertex.Tx, vertex.Ty, vertex.Tz, vertex.Ts})
+	M.Colors = append(M.Colors, Color{vertex.R, vertex.G, vertex.B, vertex.A})
+}
+
+func (M *Model) vertex(i int) VertexModern {
+	position, normal, uv := M.Positions[i], M.Normals[i], M.UVs[i]
+	tangent, color := M.Tangents[i], M.Colors[i]
+	return VertexModern{
+		position.X, position.Y, position.Z,
+		normal.X, normal.Y, normal.Z,
+		uv.U, uv.V,
+
+		tangent.X, tangent.Y, tangent.Z, tangent.S,
+		color.R, color.G, color.B, color.A,
+	}
+}
+
+func newModel(version uint8, present Attributes, numVerts int) *Model {
+	return &Model{
+		Version:   version,
+		Present:   present,
+		Positions: make([]Vector3, 0, numVerts),
+		Normals:   make([]Vector3, 0, numVerts),
+		UVs:       make([]UV, 0, numVerts),
+		Tangents:  make([]Tangent, 0, numVerts),
+		Colors:    make([]Color, 0, numVerts),
+	}
+}
+
+/* v1 has no index buffer so every face gets its own three verts */
+func (M *Mesh1) ToModel() *Model {
+	model := newModel(MeshVersion1_01, AttributeNormals|AttributeUVs, len(M.Verts))
+	for i := range M.Verts {
+		model.addVertex(M.Verts[i].Modern())
+	}
+	model.Faces = make([]Face, len(M.Verts)/3)
+	for i := range model.Faces {
+		model.Faces[i] = Face{uint32(i * 3), uint32(i*3 + 1), uint32(i*3 + 2)}
+	}
+	return model
+}
+
+func (M *Mesh2NoRgba) ToModel() *Model {
+	model := newModel(MeshVersion2, AttributeNormals|AttributeUVs|AttributeTangents, len(M.Verts))
+	for i := range M.Verts {
+		model.addVertex(M.Verts[i].Modern())
+	}
+	model.Faces = slices.Clone(M.Faces)
+	return model
+}
+
+func (M *Mesh2Rgba) ToModel() *Model {
+	model := newModel(MeshVersion2, AttributeNormals|AttributeUVs|AttributeTangents|AttributeColors, len(M.Verts))
+	for _, vertex := range M.Verts {
+		model.addVertex(vertex)
+	}
+	model.Faces = slices.Clone(M.Faces)
+	return model
+}
+
+func (M *Mesh3) ToModel() *Model {
+	present := AttributeNormals | AttributeUVs | AttributeTangents | AttributeColors
+	if len(M.Lods) > 0 {
+		present |= AttributeLods
+	}
+	model := newModel(MeshVersion3, present, len(M.Verts))
+	for _, vertex := range M.Verts {
+		model.addVertex(vertex)
+	}
+	model.Faces = slices.Clone(M.Faces)
+	model.Lods = slices.Clone(M.Lods)
+	return model
+}
+
+/* Subset each vertex belongs to, -1 for none. The first subset claiming a vertex wins */
+func vertexSubsets(numVerts int, count int, verts func(int) (uint32, uint32)) []int {
+	owners := make([]int, numVerts)
+	for i := range owners {
+		owners[i] = -1
+	}
+	for subset := 0; subset < count; subset++ {
+		begin, length := verts(subset)
+		end := min(uint64(begin)+uint64(length), uint64(numVerts))
+		for vertex := uint64(begin); vertex < end; vertex++ {
+			if owners[vertex] < 0 {
+				owners[vertex] = subset
+			}
+		}
+	}
+	return owners
+}
+
+func (M *Mesh4) ToModel() *Model {
+	present := AttributeNormals | AttributeUVs | AttributeTangents | AttributeColors
+	if len(M.Lods) > 0 {
+		present |= AttributeLods
+	}
+	if len(M.Bones) > 0 {
+		present |= AttributeSkinning
+	}
+	if len(M.MeshSubsets) > 0 {
+		present |= AttributeSubsets
+	}
+	model := newModel(MeshVersion4, present, len(M.Verts))
+	for _, vertex := range M.Verts {
+		model.addVertex(vertex)
+	}
+	model.Faces = slices.Clone(M.Faces)
+	model.Lods = slices.Clone(M.Lods)
+	model.LodType = M.Header.LodType
+	model.HighQualityLods = M.Header.NumHighQualityLods
+
+	model.Bones = make([]ModelBone, len(M.Bones))
+	for i, bone := range M.Bones {
+		model.Bones[i] = ModelBone{
+			Name:      M.BoneName(i),
+			Parent:    bone.ParentIndex,
+			LodParent: bone.LodParentIndex,
+			Culling:   bone.Culling,
+			Rotation:  [9]float32{bone.R00, bone.R01, bone.R02, bone.R10, bone.R11, bone.R12, bone.R20, bone.R21, bone.R22},
+			Position:  Vector3{bone.X, bone.Y, bone.Z},
+		}
+	}
+
+	model.Subsets = make([]ModelSubset, len(M.MeshSubsets))
+	for i, subset := range M.MeshSubsets {
+		count := min(subset.NumBonesIndicies, MaxSubsetBones)
+		model.Subsets[i] = ModelSubset{
+			subset.FacesBegin, subset.FacesLength, subset.VertsBegin, subset.VertsLength,
+			slices.Clone(subset.BoneIndicies[:count]),
+		}
+	}
+
+	/* Envelopes point into their subset, the model points at the skeleton */
+	if len(M.Bones) > 0 {
+		owners := vertexSubsets(len(M.Verts), len(model.Subsets), func(i int) (uint32, uint32) {
+			return model.Subsets[i].VertsBegin, model.Subsets[i].VertsLength
+		})
+		model.Skin = make([]SkinWeights, len(M.Verts))
+		for vertex, envelope := range M.Envelopes[:min(len(M.Envelopes), len(M.Verts))] {
+			skin := &model.Skin[vertex]
+			skin.Weights = envelope.Weights
+			for slot, local := range envelope.Bones {
+				switch owner := owners[vertex]; {
+				case owner < 0:
+					skin.Bones[slot] = uint16(local)
+				case int(local) < len(model.Subsets[owner].Bones):
+					skin.Bones[slot] = model.Subsets[owner].Bones[local]
+				default:
+					skin.Bones[slot] = NoBone
+				}
+			}
+		}
+	}
+	return model
+}
+
+/* Same as GetNormalFaces */
+func (M *Model) normalFaces() []Face {
+	if len(M.Lods) > 1 && M.Lods[1] <= uint32(len(M.Faces)) {
+		return M.Faces[:M.Lods[1]]
+	}
+	return M.Faces
+}
+
+/* [start,end) faces of each level, a model without lods is one level */
+func (M *Model) LodRanges() [][2]uint32 {
+	return lodRanges(M.Lods, len(M.Faces))
+}
+
+func (M *Model) modernVerts() []VertexModern {
+	verts := make([]VertexModern, len(M.Positions))
+	for i := range verts {
+		verts[i] = M.vertex(i)
+	}
+	return verts
+}
+
+func (M *Model) ExportV1() *Mesh1 {
+	faces := M.normalFaces()
+	mesh1 := Mesh1{
+		FaceCount: uint32(len(faces)),
+		Verts:     make([]VertexV1, 0, len(faces)*3),
+	}
+	for _, face := range faces {
+		for _, index := range [3]uint32{face.A, face.B, face.C} {
+			vertex := M.vertex(int(index))
+			mesh1.Verts = append(mesh1.Verts, vertex.Legacy())
+		}
+	}
+	return &mesh1
+}
+
+/* Models without colors go back to 36 byte verts */
+func (M *Model) ExportV2() Mesh2 {
+	faces := slices.Clone(M.normalFaces())
+	verts := M.modernVerts()
+	header := MeshHeader2{Header2Size, VertexModernSize, FaceSize, uint32(len(verts)), uint32(len(faces))}
+	if M.Present.Has(AttributeColors) {
+		return &Mesh2Rgba{header, verts, faces}
+	}
+
+	header.VertexSize = VertexNoRgbaSize
+	noRgba := Mesh2NoRgba{Header: header, Verts: make([]VertexNoRgba, len(verts)), Faces: faces}
+	for i := range verts {
+		noRgba.Verts[i] = verts[i].NoColor()
+	}
+	return &noRgba
+}
+
+/*
+v2 models have no lods, v3 and v4 get the one level that covers every face.
+Every export and the transcoder share it so they write the same bytes.
+*/
+func singleLod(numFaces uint32) []uint32 {
+	if numFaces == 0 {
+		return []uint32{}
+	}
+	return []uint32{0, numFaces}
+}
+
+func (M *Model) exportLods() []uint32 {
+	if len(M.Lods) == 0 {
+		return singleLod(uint32(len(M.Faces)))
+	}
+	return slices.Clone(M.Lods)
+}
+
+func (M *Model) ExportV3() *Mesh3 {
+	return &Mesh3{
+		Header: MeshHeader3{
+			Header3Size, VertexModernSize, FaceSize, 4,
+			uint16(len(M.exportLods())), uint32(len(M.Positions)), uint32(len(M.Faces)),
+		},
+		Verts: M.modernVerts(),
+		Faces: slices.Clone(M.Faces),
+		Lods:  M.exportLods(),
+	}
+}
+
+/*
+Skin bones that are missing from their subset are written as 0xFF. When a
+subset lists more than MaxSubsetBones bones, or a vertex outside every subset
+uses a bone an envelope byte cant hold, the subsets are rebuilt from the skin
+instead of dropping bones.
+*/
+func (M *Model) ExportV4() *Mesh4 {
+	mesh4 := Mesh4{
+		Verts:       M.modernVerts(),
+		Envelopes:   make([]Envelope, len(M.Positions)),
+		Faces:       slices.Clone(M.Faces),
+		Lods:        M.exportLods(),
+		Bones:       make([]Bone, len(M.Bones)),
+		NameTable:   []byte{},
+		MeshSubsets: make([]MeshSubset, len(M.Subsets)),
+	}
+
+	for i, bone := range M.Bones {
+		rotation := bone.Rotation
+		mesh4.Bones[i] = Bone{
+			uint32(len(mesh4.NameTable)), bone.Parent, bone.LodParent, bone.Culling,
+			rotation[0], rotation[1], rotation[2],
+			rotation[3], rotation[4], rotation[5],
+			rotation[6], rotation[7], rotation[8],
+			bone.Position.X, bone.Position.Y, bone.Position.Z,
+		}
+		mesh4.NameTable = append(append(mesh4.NameTable, bone.Name...), 0)
+	}
+
+	for i, subset := range M.Subsets {
+		meshSubset := MeshSubset{
+			FacesBegin:       subset.FacesBegin,
+			FacesLength:      subset.FacesLength,
+			VertsBegin:       subset.VertsBegin,
+			VertsLength:      subset.VertsLength,
+			NumBonesIndicies: uint32(min(len(subset.Bones), MaxSubsetBones)),
+		}
+		for slot := range meshSubset.BoneIndicies {
+			meshSubset.BoneIndicies[slot] = NoBone
+		}
+		copy(meshSubset.BoneIndicies[:], subset.Bones)
+		mesh4.MeshSubsets[i] = meshSubset
+	}
+
+	if len(M.Bones) > 0 {
+		owners := vertexSubsets(len(M.Positions), len(mesh4.MeshSubsets), func(i int) (uint32, uint32) {
+			return mesh4.MeshSubsets[i].VertsBegin, mesh4.MeshSubsets[i].VertsLength
+		})
+		for vertex, skin := range M.Skin[:min(len(M.Skin), len(M.Positions))] {
+			envelope := &mesh4.Envelopes[vertex]
+			envelope.Weights = skin.Weights
+			for slot, bone := range skin.Bones {
+				envelope.Bones[slot] = 0xFF
+				if owners[vertex] < 0 {
+					envelope.Bones[slot] = byte(bone)
+				} else if local := slices.Index(M.Subsets[owners[vertex]].Bones, bone); local >= 0 && local < MaxSubsetBones {
+					envelope.Bones[slot] = byte(local)
+				}
+			}
+		}
+	}
+
+	mesh4.Header = MeshHeader4{
+		SizeOf_MeshHeader:        Header4Size,
+		LodType:                  M.LodType,
+		NumVerts:                 uint32(len(mesh4.Verts)),
+		NumFaces:                 uint32(len(mesh4.Faces)),
+		NumLods:                  ushort(len(mesh4.Lods)),
+		NumBones:                 ushort(len(mesh4.Bones)),
+		SizeOf_bone_names_Buffer: uint32(len(mesh4.NameTable)),
+		NumSubsets:               ushort(len(mesh4.MeshSubsets)),
+		NumHighQualityLods:       M.HighQualityLods,
+	}
+	if M.needsPartition() {
+		return partitionSkin(&mesh4, M.Skin)
+	}
+	return &mesh4
+}
+
+func (M *Model) needsPartition() bool {
+	if len(M.Bones) == 0 {
+		return false
+	}
+	for _, subset := range M.Subsets {
+		if len(subset.Bones) > MaxSubsetBones {
+			return true
+		}
+	}
+	owners := vertexSubsets(len(M.Positions), len(M.Subsets), func(i int) (uint32, uint32) {
+		return M.Subsets[i].VertsBegin, M.Subsets[i].VertsLength
+	})
+	for vertex, skin := range M.Skin[:min(len(M.Skin), len(M.Positions))] {
+		if owners[vertex] >= 0 {
+			continue
+		}
+		for slot, bone := range skin.Bones {
+			if skin.Weights[slot] > 0 && bone > 0xFF && bone != NoBone {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/* Written as the exact version it came from, hand built models are written as v4 */
+func (M *Model) Write(stream io.Writer) error {
+	version := cmp.Or(M.Version, MeshVersion4)
+	if version == MeshVersion1 {
+		/* 1.00 is read but never written */
+		version = MeshVersion1_01
+	}
+	return writeVersion(M, version, stream)
+}
+
+func NewModel(mesh Mesh) (*Model, error) {
+	switch typed := mesh.(type) {
+	case *Mesh2NoRgba:
+		return typed.ToModel(), nil
+	case *Mesh2Rgba:
+		return typed.ToModel(), nil
+	case *Mesh3:
+		return typed.ToModel(), nil
+	case *Mesh4:
+		return typed.ToModel(), nil
+	case *Model:
+		return typed, nil
+	default:
+		return nil, ErrBadMeshVersion
+	}
+}
+
+func DecodeModel(stream io.Reader) (*Model, error) {
+	return DecodeModelOptions(stream, DecodeOptions{Limits: DefaultLimits})
+}
+
+/* Keeps the exact version read, partial meshes from lenient decodes come back with their error */
+func DecodeModelOptions(stream io.Reader, opts DecodeOptions) (*Model, error) {
+	decoded, version, err := decodeReader(newMeshReader(stream, opts, 0), opts)
+	if decoded == nil {
+		return nil, err
+	}
+	model, modelErr := NewModel(decoded)
+	if modelErr != nil {
+		return nil, modelErr
+	}
+	model.Version = version
+	return model, err
+}
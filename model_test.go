@@ -0,0 +1,193 @@
+package mesh_test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/MojaveMF/mesh"
+	"os"
+	"reflect"
+	"testing"
+)
+
+var _ mesh.Mesh = &mesh.Model{}
+
+func TestModelRoundTrip(t *testing.T) {
+	for _, path := range []string{"./testdata/output.v2", "./testdata/output.v3", "./testdata/output.v4"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		model, err := mesh.DecodeModel(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		if !bytes.Equal(encode(t, model), data) {
+			t.Errorf("%s: model did not write back the same bytes", path)
+		}
+
+		/* Every export has to match the one from the version specific type */
+		decoded := decodeFile(t, path)
+		for _, version := range []uint8{mesh.MeshVersion2, mesh.MeshVersion3, mesh.MeshVersion4} {
+			fromModel := mesh.EncodeMeshVersion(model, version)
+			fromMesh := mesh.EncodeMeshVersion(decoded, version)
+			if !reflect.DeepEqual(fromModel.ExportV4().ToModel(), fromMesh.ExportV4().ToModel()) {
+				t.Errorf("%s: export to %d differs", path, version)
+			}
+		}
+	}
+}
+
+func TestModelSkippedForV3V4(t *testing.T) {
+	lodless := twoTriangles()
+	lodless.Lods, lodless.Header.NumLods = nil, 0
+	sources := []mesh.Mesh{decodeFile(t, "./testdata/output.v3"), decodeFile(t, "./testdata/output.v4"), lodless, lodless.ExportV4()}
+
+	/* Going straight between v3 and v4 has to write what the Model would, the same version is written as is */
+	for i, source := range sources {
+		model, err := mesh.NewModel(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exports := map[uint8]mesh.Mesh{mesh.MeshVersion3: model.ExportV3(), mesh.MeshVersion4: model.ExportV4()}
+		for version, exported := range exports {
+			if _, same := source.(*mesh.Mesh3); same == (version == mesh.MeshVersion3) {
+				continue
+			}
+			expected := bytes.Buffer{}
+			if err := exported.Write(&expected); err != nil {
+				t.Fatal(err)
+			}
+			output := bytes.Buffer{}
+			if err := mesh.NewEncoder(&output, mesh.EncodeVersion(version)).Encode(source); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(output.Bytes(), expected.Bytes()) {
+				t.Errorf("source %d to %d: differs from the Model export", i, version)
+			}
+		}
+	}
+}
+
+func TestModelV1(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := mesh.MeshStream1{bytes.NewReader(data[13:])}
+	mesh1, err := stream.LoadMesh()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model := mesh1.ToModel()
+	if model.Present.Has(mesh.AttributeColors) || !model.Present.Has(mesh.AttributeNormals|mesh.AttributeUVs) {
+		t.Errorf("unexpected attributes %b", model.Present)
+	}
+	if len(model.Faces) != int(mesh1.FaceCount) {
+		t.Errorf("expected %d faces got %d", mesh1.FaceCount, len(model.Faces))
+	}
+	expected := bytes.Buffer{}
+	if err := mesh1.Write(&expected); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encode(t, model), expected.Bytes()) {
+		t.Error("v1 model did not write back the same text")
+	}
+}
+
+func TestModelNoColors(t *testing.T) {
+	model, err := mesh.DecodeModel(bytes.NewReader(noRgbaMesh()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.Present.Has(mesh.AttributeColors) {
+		t.Error("36 byte verts reported colors")
+	}
+	if _, ok := model.ExportV2().(*mesh.Mesh2NoRgba); !ok {
+		t.Error("model without colors exported colors")
+	}
+}
+
+func TestModelSkin(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+	model := mesh4.ToModel()
+	if !model.Present.Has(mesh.AttributeSkinning | mesh.AttributeSubsets | mesh.AttributeLods) {
+		t.Fatalf("unexpected attributes %b", model.Present)
+	}
+	if len(model.Skin) != len(model.Positions) || model.Bones[1].Name != mesh4.BoneName(1) {
+		t.Fatal("skeleton or skin missing")
+	}
+	for vertex, skin := range model.Skin {
+		for slot, bone := range skin.Bones {
+			if skin.Weights[slot] > 0 && int(bone) >= len(model.Bones) {
+				t.Fatalf("vertex %d weights bone %d which is not in the skeleton", vertex, bone)
+			}
+		}
+	}
+	if !reflect.DeepEqual(model.ExportV4().Envelopes, mesh4.Envelopes) {
+		t.Error("envelopes changed going through the model")
+	}
+}
+
+func TestModelWriteMinorVersion(t *testing.T) {
+	for path, line := range map[string]string{"./testdata/output.v3": "version 3.01\n", "./testdata/output.v4": "version 4.01\n"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		minor := append([]byte(line), data[len(line):]...)
+		model, err := mesh.DecodeModel(bytes.NewReader(minor))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encode(t, model), minor) {
+			t.Errorf("%s was not written back as %q", path, line)
+		}
+	}
+}
+
+/* 300 bones and no subsets, vertex i is weighted to bone i*10 so the last ones are past a byte */
+func TestModelExportWideSkeleton(t *testing.T) {
+	model := &mesh.Model{}
+	for b := 0; b < 300; b++ {
+		model.Bones = append(model.Bones, mesh.ModelBone{Name: fmt.Sprintf("Bone%d", b), Parent: mesh.NoBone, LodParent: mesh.NoBone})
+	}
+	for v := 0; v < 30; v++ {
+		model.Positions = append(model.Positions, mesh.Vector3{X: float32(v)})
+		model.Normals = append(model.Normals, mesh.Vector3{})
+		model.UVs = append(model.UVs, mesh.UV{})
+		model.Tangents = append(model.Tangents, mesh.Tangent{})
+		model.Colors = append(model.Colors, mesh.Color{})
+		model.Skin = append(model.Skin, mesh.SkinWeights{Bones: [4]uint16{uint16(v * 10), mesh.NoBone, mesh.NoBone, mesh.NoBone}, Weights: [4]byte{255}})
+	}
+	for v := uint32(0); v < 30; v += 3 {
+		model.Faces = append(model.Faces, mesh.Face{A: v, B: v + 1, C: v + 2})
+	}
+	model.Present = mesh.AttributeSkinning
+
+	mesh4 := model.ExportV4()
+	if len(mesh4.MeshSubsets) < 2 {
+		t.Fatalf("got %d subsets", len(mesh4.MeshSubsets))
+	}
+	if err := mesh4.Validate().Err(); err != nil {
+		t.Fatal(err)
+	}
+	back := mesh4.ToModel()
+	for v, skin := range back.Skin {
+		if skin.Bones[0] != uint16(v*10) {
+			t.Errorf("vertex %d is weighted to bone %d", v, skin.Bones[0])
+		}
+	}
+
+	/* The same skin inside one subset that lists every bone */
+	model.Subsets = []mesh.ModelSubset{{FacesLength: uint32(len(model.Faces)), VertsLength: 30}}
+	for b := range model.Bones {
+		model.Subsets[0].Bones = append(model.Subsets[0].Bones, uint16(b))
+	}
+	back = model.ExportV4().ToModel()
+	for v, skin := range back.Skin {
+		if skin.Bones[0] != uint16(v*10) {
+			t.Errorf("vertex %d is weighted to bone %d with a subset", v, skin.Bones[0])
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package mesh
+
+import (
+	"errors"
+	"io"
+	"slices"
+)
+
+var ErrNoSupportedVersion = errors.New("no supported version can be written")
+
+/*
+Oldest first, registered versions count as newer than the built in ones in
+the order they were registered. The version constants are flags so their
+numeric order means nothing.
+*/
+func versionOrder() []uint8 {
+	order := make([]uint8, 0, len(builtinVersions))
+	for _, codec := range Codecs() {
+		if codec.Version != 0 {
+			order = append(order, codec.Version)
+		}
+	}
+	return order
+}
+
+/*
+Every version up to and including max, built in ones as flags and registered
+ones listed. Both are empty when max isnt a version.
+*/
+func versionsUpTo(max uint8) (uint8, []uint8) {
+	order := versionOrder()
+	flags, registered := uint8(0), []uint8(nil)
+	for _, version := range order[:slices.Index(order, max)+1] {
+		if builtinVersion(version) {
+			flags |= version
+		} else {
+			registered = append(registered, version)
+		}
+	}
+	return flags, registered
+}
+
+/* Built in versions are checked against flags, registered ones against a list. Nothing set allows everything */
+func allowed(flags uint8, registered []uint8, version uint8) bool {
+	if flags == 0 && len(registered) == 0 {
+		return true
+	}
+	if builtinVersion(version) {
+		return flags&version != 0
+	}
+	return slices.Contains(registered, version)
+}
+
+/* 1.00 meshes are stored at a different scale so its codec has no encoder */
+func writable(version uint8) bool {
+	codec, ok := codecForVersion(version)
+	return ok && codec.Encode != nil
+}
+
+/* How a conversion was done, cheapest first */
+type Strategy uint8
+
+const (
+	StrategyPassThrough Strategy = iota /* bytes copied as they are */
+	StrategyHeaderOnly                  /* only the version line changed */
+	StrategyStreaming                   /* converted section by section */
+	StrategyReencode                    /* decoded in full and written again */
+)
+
+func (S Strategy) String() string {
+	switch S {
+	case StrategyPassThrough:
+		return "pass-through"
+	case StrategyHeaderOnly:
+		return "header-only"
+	case StrategyStreaming:
+		return "streaming"
+	default:
+		return "re-encode"
+	}
+}
+
+type Transforms uint8
+
+const (
+	TransformStripBones Transforms = 1 << iota /* drops bones, envelopes and subsets */
+	TransformLod0Only                          /* drops every face and vertex not used by LOD 0 */
+)
+
+/*
+What a client can take. Accept and Supported are MeshVersion flags or'd
+together, registered versions are not flags so they go in AcceptVersions and
+SupportedVersions. Leaving both empty allows every version. Inputs Supported
+already takes are left alone unless a transform has to change them.
+*/
+type Policy struct {
+	Accept            uint8
+	Supported         uint8
+	AcceptVersions    []uint8
+	SupportedVersions []uint8
+	Target            uint8 /* what unsupported inputs become, 0 picks the closest supported version */
+	Transforms        Transforms
+	Options           DecodeOptions
+}
+
+type ConversionReport struct {
+	Input    uint8
+	Output   uint8
+	Strategy Strategy
+}
+
+func (P *Policy) supports(version uint8) bool {
+	return allowed(P.Supported, P.SupportedVersions, version)
+}
+
+/* Prefers the newest supported version older than the input, then the oldest newer one */
+func (P *Policy) target(version uint8) (uint8, error) {
+	if P.supports(version) {
+		return version, nil
+	}
+	if P.Target != 0 {
+		return P.Target, nil
+	}
+
+	order := versionOrder()
+	for _, sibling := range order {
+		if versionFamily(sibling) != 0 && versionFamily(sibling) == versionFamily(version) && P.supports(sibling) && writable(sibling) {
+			return sibling, nil
+		}
+	}
+	rank := slices.Index(order, version)
+	for i := rank - 1; i >= 0; i-- {
+		if P.supports(order[i]) && writable(order[i]) {
+			return order[i], nil
+		}
+	}
+	for _, newer := range order[rank+1:] {
+		if P.supports(newer) && writable(newer) {
+			return newer, nil
+		}
+	}
+	return 0, ErrNoSupportedVersion
+}
+
+/* Transforms that still have something to do once the mesh is in the target version */
+func (P *Policy) transforms(version uint8, target uint8) Transforms {
+	from, to := versionFamily(version), versionFamily(target)
+	transforms := P.Transforms
+	if from != MeshVersion4 || to != MeshVersion4 {
+		transforms &^= TransformStripBones
+	}
+	/* v2 exports only cut the faces so the unused verts still need dropping */
+	if from == MeshVersion1 || from == MeshVersion2 {
+		transforms &^= TransformLod0Only
+	}
+	return transforms
+}
+
+/*
+Reads a mesh from src and writes what the policy allows to dst using the
+cheapest way that works. Pass-through copies without checking the mesh.
+*/
+func (P Policy) Convert(src io.Reader, dst io.Writer) (*ConversionReport, error) {
+	reader := newMeshReader(src, P.Options, 0)
+	start := reader.position()
+	version, err := MeshVersion(reader)
+	if err != nil {
+		return nil, &DecodeError{0, SectionHeader, -1, start, err}
+	}
+	reader.version = version
+	if !allowed(P.Accept, P.AcceptVersions, version) {
+		return nil, &DecodeError{version, SectionHeader, -1, start, ErrVersionNotAllowed}
+	}
+
+	target, err := P.target(version)
+	if err != nil {
+		return nil, err
+	}
+	report := ConversionReport{Input: version, Output: target}
+	transforms := P.transforms(version, target)
+
+	switch {
+	case target == version && transforms == 0:
+		report.Strategy = StrategyPassThrough
+		err = copyMesh(reader, target, dst)
+	case sameLayout(version, target) && transforms == 0:
+		report.Strategy = StrategyHeaderOnly
+		err = copyMesh(reader, target, dst)
+	case transforms == 0:
+		report.Strategy, err = transcode(reader, version, target, dst)
+	default:
+		report.Strategy = StrategyReencode
+		err = reencode(reader, version, target, transforms, dst)
+	}
+	return &report, err
+}
+
+/* Only 3.xx and 4.xx have minor versions that share a layout */
+func sameLayout(version uint8, target uint8) bool {
+	family := versionFamily(version)
+	return family == versionFamily(target) && (family == MeshVersion3 || family == MeshVersion4)
+}
+
+func copyMesh(R *meshReader, target uint8, dst io.Writer) error {
+	if _, err := io.WriteString(dst, versionLine(target)); err != nil {
+		return err
+	}
+	_, err := io.Copy(dst, R)
+	return err
+}
+
+func reencode(R *meshReader, version uint8, target uint8, transforms Transforms, dst io.Writer) error {
+	decoded, err := decodeMeshOptions(R, version, R.opts)
+	if decoded == nil {
+		return err
+	}
+	if transforms&TransformLod0Only != 0 {
+		decoded = lod0Only(decoded)
+	}
+	if transforms&TransformStripBones != 0 {
+		decoded = stripBones(decoded)
+	}
+	if writeErr := writeVersion(decoded, target, dst); writeErr != nil {
+		return writeErr
+	}
+	/* Lenient decodes hand back what was lost */
+	return err
+}
+
+func lod0Marks(faces []Face, lods []uint32, numVerts int) ([]bool, []bool) {
+	lod0 := lodRanges(lods, len(faces))[0]
+	keepFace := make([]bool, len(faces))
+	for i := range keepFace {
+		keepFace[i] = uint32(i) >= lod0[0] && uint32(i) < lod0[1]
+	}
+	return keepFace, usedVerts(numVerts, faces, keepFace)
+}
+
+/* Copies that only hold LOD 0 as their single level */
+func lod0Only(mesh Mesh) Mesh {
+	switch typed := mesh.(type) {
+	case *Mesh3:
+		newMesh := typed.filter(lod0Marks(typed.Faces, typed.Lods, len(typed.Verts)))
+		newMesh.Lods = []uint32{0, uint32(len(newMesh.Faces))}
+		newMesh.Header.NumLods = uint16(len(newMesh.Lods))
+		return newMesh
+	case *Mesh4:
+		newMesh := typed.filter(lod0Marks(typed.Faces, typed.Lods, len(typed.Verts)))
+		newMesh.Lods = []uint32{0, uint32(len(newMesh.Faces))}
+		newMesh.Header.NumLods = ushort(len(newMesh.Lods))
+		newMesh.Header.NumHighQualityLods = min(newMesh.Header.NumHighQualityLods, 1)
+		return newMesh
+	default:
+		return mesh
+	}
+}
+
+func stripBones(mesh Mesh) Mesh {
+	typed, ok := mesh.(*Mesh4)
+	if !ok {
+		return mesh
+	}
+	newMesh := *typed
+	newMesh.Envelopes = make([]Envelope, len(typed.Verts))
+	newMesh.Bones = []Bone{}
+	newMesh.NameTable = []byte{}
+	newMesh.MeshSubsets = []MeshSubset{}
+	newMesh.Header.NumBones = 0
+	newMesh.Header.SizeOf_bone_names_Buffer = 0
+	newMesh.Header.NumSubsets = 0
+	return &newMesh
+}
@@ -0,0 +1,159 @@
+package mesh_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/MojaveMF/mesh"
+	"io"
+	"os"
+	"testing"
+)
+
+func convert(t *testing.T, policy mesh.Policy, src io.Reader) ([]byte, *mesh.ConversionReport) {
+	output := bytes.Buffer{}
+	report, err := policy.Convert(src, &output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output.Bytes(), report
+}
+
+func TestPolicyStrategies(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, report := convert(t, mesh.Policy{}, bytes.NewReader(data))
+	if report.Strategy != mesh.StrategyPassThrough || !bytes.Equal(output, data) {
+		t.Errorf("expected an untouched copy got %s", report.Strategy)
+	}
+
+	newer := append([]byte("version 4.01\n"), data[13:]...)
+	output, report = convert(t, mesh.Policy{Supported: mesh.MeshVersion4}, bytes.NewReader(newer))
+	if report.Strategy != mesh.StrategyHeaderOnly || report.Output != mesh.MeshVersion4 || !bytes.Equal(output, data) {
+		t.Errorf("expected 4.01 to only lose its version line got %s", report.Strategy)
+	}
+
+	expected := bytes.Buffer{}
+	decoded := decodeFile(t, "./testdata/output.v4")
+	if err := decoded.ExportV2().Write(&expected); err != nil {
+		t.Fatal(err)
+	}
+	old := mesh.Policy{Supported: mesh.MeshVersion2 | mesh.MeshVersion3}
+	output, report = convert(t, mesh.Policy{Supported: mesh.MeshVersion2}, bytes.NewReader(data))
+	if report.Strategy != mesh.StrategyStreaming || !bytes.Equal(output, expected.Bytes()) {
+		t.Errorf("expected a streamed v2 got %s", report.Strategy)
+	}
+	output, report = convert(t, mesh.Policy{Supported: mesh.MeshVersion2}, plainReader{bytes.NewReader(data)})
+	if report.Strategy != mesh.StrategyReencode || !bytes.Equal(output, expected.Bytes()) {
+		t.Errorf("expected a re-encoded v2 got %s", report.Strategy)
+	}
+
+	/* Newest older version wins */
+	if _, report = convert(t, old, bytes.NewReader(data)); report.Output != mesh.MeshVersion3 {
+		t.Errorf("expected v3 got %d", report.Output)
+	}
+}
+
+func TestPolicyTransforms(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := mesh.Policy{Transforms: mesh.TransformStripBones | mesh.TransformLod0Only}
+	output, report := convert(t, policy, bytes.NewReader(data))
+	if report.Strategy != mesh.StrategyReencode {
+		t.Errorf("expected transforms to force a re-encode got %s", report.Strategy)
+	}
+	converted, err := mesh.DecodeMesh(bytes.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := decodeFile(t, "./testdata/output.v4").ExportV4()
+	mesh4 := converted.ExportV4()
+	if mesh4.Header.NumBones != 0 || mesh4.Header.NumSubsets != 0 {
+		t.Errorf("bones were kept")
+	}
+	if len(mesh4.Lods) != 2 || mesh4.Lods[1] != original.Lods[1] || len(mesh4.Faces) != int(original.Lods[1]) {
+		t.Errorf("expected only LOD 0 got lods %v", mesh4.Lods)
+	}
+
+	/* v2 only keeps LOD 0 faces but the verts the other lods use have to go too */
+	used := map[uint32]bool{}
+	for _, face := range original.Faces[:original.Lods[1]] {
+		used[face.A], used[face.B], used[face.C] = true, true, true
+	}
+	policy = mesh.Policy{Supported: mesh.MeshVersion2, Transforms: mesh.TransformLod0Only}
+	output, report = convert(t, policy, bytes.NewReader(data))
+	if report.Strategy != mesh.StrategyReencode {
+		t.Errorf("expected LOD 0 only to force a re-encode got %s", report.Strategy)
+	}
+	if converted, err = mesh.DecodeMesh(bytes.NewReader(output)); err != nil {
+		t.Fatal(err)
+	}
+	if numVerts := len(converted.ExportV4().Verts); numVerts != len(used) {
+		t.Errorf("expected %d verts used by LOD 0 got %d", len(used), numVerts)
+	}
+
+	/* Nothing to strip once the mesh is v3 */
+	policy.Supported = mesh.MeshVersion3
+	policy.Transforms = mesh.TransformStripBones
+	if _, report = convert(t, policy, bytes.NewReader(data)); report.Strategy != mesh.StrategyStreaming {
+		t.Errorf("expected a streamed v3 got %s", report.Strategy)
+	}
+}
+
+func TestPolicyAccept(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := mesh.Policy{Accept: mesh.MeshVersion4 | mesh.MeshVersion4_1}
+	if _, err := policy.Convert(bytes.NewReader(data), io.Discard); !errors.Is(err, mesh.ErrVersionNotAllowed) {
+		t.Errorf("expected v3 to be refused got %v", err)
+	}
+
+	policy = mesh.Policy{Supported: mesh.MeshVersion1}
+	if _, err := policy.Convert(bytes.NewReader(data), io.Discard); !errors.Is(err, mesh.ErrNoSupportedVersion) {
+		t.Errorf("expected no version to be found got %v", err)
+	}
+}
+
+func TestMeshDecodeLayer(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := bytes.Buffer{}
+	if err := decodeFile(t, "./testdata/output.v4").ExportV3().Write(&expected); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, layer := range []struct {
+		max, export uint8
+		expected    []byte
+	}{
+		{mesh.MeshVersion4_1, mesh.MeshVersion2, data},
+		{mesh.MeshVersion3_01, mesh.MeshVersion3, expected.Bytes()},
+		{0, mesh.MeshVersion3, expected.Bytes()},
+	} {
+		output := bytes.Buffer{}
+		if err := mesh.MeshDecodeLayer(layer.max, layer.export)(bytes.NewReader(data), &output); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(output.Bytes(), layer.expected) {
+			t.Errorf("max %d export %d: unexpected output", layer.max, layer.export)
+		}
+	}
+}
+
+func TestMeshDecodeLayerLimits(t *testing.T) {
+	/* MultiReader hides the length so only the limits can stop it */
+	err := mesh.MeshDecodeLayer(mesh.MeshVersion1_01, mesh.MeshVersion1_01)(io.MultiReader(bytes.NewReader(hugeV2())), io.Discard)
+	limitErr := &mesh.LimitError{}
+	if !errors.As(err, &limitErr) || limitErr.Field != "verts" {
+		t.Errorf("expected verts limit error got %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+package mesh
+
+import (
+	"io"
+	"math"
+	"slices"
+)
+
+/* Bits per component, 0 leaves that attribute alone */
+type QuantizeOptions struct {
+	PositionBits uint8
+	NormalBits   uint8
+	UVBits       uint8
+
+	/* Merge verts that end up identical after snapping */
+	Weld bool
+}
+
+type AttributeError struct {
+	Max  float32
+	Mean float32
+}
+
+type SizeChange struct {
+	Version uint8
+	Before  int64
+	After   int64
+}
+
+func (S SizeChange) Saved() int64 {
+	return S.Before - S.After
+}
+
+type QuantizeReport struct {
+	Position AttributeError
+	Normal   AttributeError
+	UV       AttributeError
+
+	VertsBefore int
+	VertsAfter  int
+
+	/* Encoded size for v2, v3 and v4 output */
+	Sizes []SizeChange
+}
+
+/* Snaps to 2^bits evenly spaced steps between min and max */
+type quantizeAxis struct {
+	min, step float32
+}
+
+func newQuantizeAxis(min, max float32, bits uint8) quantizeAxis {
+	if bits == 0 || bits > 24 || max <= min {
+		return quantizeAxis{min: min}
+	}
+	return quantizeAxis{min, (max - min) / float32(uint32(1)<<bits-1)}
+}
+
+func (Q quantizeAxis) snap(value float32) float32 {
+	if Q.step == 0 || math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+		return value
+	}
+	return Q.min + float32(math.Round(float64((value-Q.min)/Q.step)))*Q.step
+}
+
+type quantizer struct {
+	position [3]quantizeAxis
+	normal   quantizeAxis
+	uv       [2]quantizeAxis
+
+	positionError, normalError, uvError errorSum
+}
+
+type errorSum struct {
+	max   float32
+	total float64
+	count int
+}
+
+func (E *errorSum) add(values ...float32) {
+	var sum float64
+	for _, value := range values {
+		sum += float64(value) * float64(value)
+	}
+	distance := float32(math.Sqrt(sum))
+	E.max = max(E.max, distance)
+	E.total += float64(distance)
+	E.count++
+}
+
+func (E *errorSum) result() AttributeError {
+	if E.count == 0 {
+		return AttributeError{}
+	}
+	return AttributeError{E.max, float32(E.total / float64(E.count))}
+}
+
+type vertexAttributes struct {
+	px, py, pz *float32
+	nx, ny, nz *float32
+	tu, tv     *float32
+}
+
+func newQuantizer(count int, vertex func(int) vertexAttributes, opts QuantizeOptions) *quantizer {
+	mins := [5]float32{float32(math.Inf(1)), float32(math.Inf(1)), float32(math.Inf(1)), float32(math.Inf(1)), float32(math.Inf(1))}
+	maxs := [5]float32{float32(math.Inf(-1)), float32(math.Inf(-1)), float32(math.Inf(-1)), float32(math.Inf(-1)), float32(math.Inf(-1))}
+	for i := 0; i < count; i++ {
+		attributes := vertex(i)
+		for axis, value := range [5]float32{*attributes.px, *attributes.py, *attributes.pz, *attributes.tu, *attributes.tv} {
+			if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+				continue
+			}
+			mins[axis] = min(mins[axis], value)
+			maxs[axis] = max(maxs[axis], value)
+		}
+	}
+
+	return &quantizer{
+		position: [3]quantizeAxis{
+			newQuantizeAxis(mins[0], maxs[0], opts.PositionBits),
+			newQuantizeAxis(mins[1], maxs[1], opts.PositionBits),
+			newQuantizeAxis(mins[2], maxs[2], opts.PositionBits),
+		},
+		normal: newQuantizeAxis(-1, 1, opts.NormalBits),
+		uv: [2]quantizeAxis{
+			newQuantizeAxis(mins[3], maxs[3], opts.UVBits),
+			newQuantizeAxis(mins[4], maxs[4], opts.UVBits),
+		},
+	}
+}
+
+func (Q *quantizer) snap(V vertexAttributes) {
+	px, py, pz := *V.px, *V.py, *V.pz
+	*V.px, *V.py, *V.pz = Q.position[0].snap(px), Q.position[1].snap(py), Q.position[2].snap(pz)
+	Q.positionError.add(*V.px-px, *V.py-py, *V.pz-pz)
+
+	nx, ny, nz := *V.nx, *V.ny, *V.nz
+	*V.nx, *V.ny, *V.nz = Q.normal.snap(nx), Q.normal.snap(ny), Q.normal.snap(nz)
+	Q.normalError.add(*V.nx-nx, *V.ny-ny, *V.nz-nz)
+
+	tu, tv := *V.tu, *V.tv
+	*V.tu, *V.tv = Q.uv[0].snap(tu), Q.uv[1].snap(tv)
+	Q.uvError.add(*V.tu-tu, *V.tv-tv)
+}
+
+func (Q *quantizer) report(report *QuantizeReport) {
+	report.Position = Q.positionError.result()
+	report.Normal = Q.normalError.result()
+	report.UV = Q.uvError.result()
+}
+
+func (V *VertexModern) attributes() vertexAttributes {
+	return vertexAttributes{&V.Px, &V.Py, &V.Pz, &V.Nx, &V.Ny, &V.Nz, &V.Tu, &V.Tv}
+}
+
+func (V *VertexNoRgba) attributes() vertexAttributes {
+	return vertexAttributes{&V.Px, &V.Py, &V.Pz, &V.Nx, &V.Ny, &V.Nz, &V.Tu, &V.Tv}
+}
+
+/*
+Marks the first copy of every distinct key and points faces at it, faces are
+changed in place so only pass a copy
+*/
+func weldKeys[K comparable](count int, key func(int) K, faces []Face) []bool {
+	keep := make([]bool, count)
+	first := make([]uint32, count)
+	seen := make(map[K]uint32, count)
+	for i := 0; i < count; i++ {
+		k := key(i)
+		if index, ok := seen[k]; ok {
+			first[i] = index
+			continue
+		}
+		seen[k] = uint32(i)
+		first[i] = uint32(i)
+		keep[i] = true
+	}
+	for i, face := range faces {
+		if !faceInRange(face, count) {
+			continue
+		}
+		faces[i] = Face{first[face.A], first[face.B], first[face.C]}
+	}
+	return keep
+}
+
+func allTrue(count int) []bool {
+	keep := make([]bool, count)
+	for i := range keep {
+		keep[i] = true
+	}
+	return keep
+}
+
+func encodedSize(mesh Mesh) int64 {
+	size, err := writeTo(io.Discard, mesh.Write)
+	if err != nil {
+		return -1
+	}
+	return size
+}
+
+func encodedSizes(mesh Mesh) [3]int64 {
+	return [3]int64{
+		encodedSize(mesh.ExportV2()),
+		encodedSize(mesh.ExportV3()),
+		encodedSize(mesh.ExportV4()),
+	}
+}
+
+func finishReport(report *QuantizeReport, before, after Mesh) {
+	sizesBefore, sizesAfter := encodedSizes(before), encodedSizes(after)
+	for i, version := range []uint8{MeshVersion2, MeshVersion3, MeshVersion4} {
+		report.Sizes = append(report.Sizes, SizeChange{version, sizesBefore[i], sizesAfter[i]})
+	}
+}
+
+/* Returns a quantized copy, the source mesh is not touched */
+func (M *Mesh2NoRgba) Quantize(opts QuantizeOptions) (*Mesh2NoRgba, *QuantizeReport) {
+	verts := append([]VertexNoRgba{}, M.Verts...)
+	faces := append([]Face{}, M.Faces...)
+	Q := newQuantizer(len(verts), func(i int) vertexAttributes { return verts[i].attributes() }, opts)
+	for i := range verts {
+		Q.snap(verts[i].attributes())
+	}
+
+	keep := allTrue(len(verts))
+	if opts.Weld {
+		keep = weldKeys(len(verts), func(i int) VertexNoRgba { return verts[i] }, faces)
+	}
+	snapped := Mesh2NoRgba{M.Header, verts, faces}
+	newMesh := snapped.filter(allTrue(len(faces)), keep)
+
+	report := QuantizeReport{VertsBefore: len(M.Verts), VertsAfter: len(newMesh.Verts)}
+	Q.report(&report)
+	finishReport(&report, M, newMesh)
+	return newMesh, &report
+}
+
+func (M *Mesh2Rgba) Quantize(opts QuantizeOptions) (*Mesh2Rgba, *QuantizeReport) {
+	verts := append([]VertexModern{}, M.Verts...)
+	faces := append([]Face{}, M.Faces...)
+	Q := newQuantizer(len(verts), func(i int) vertexAttributes { return verts[i].attributes() }, opts)
+	for i := range verts {
+		Q.snap(verts[i].attributes())
+	}
+
+	keep := allTrue(len(verts))
+	if opts.Weld {
+		keep = weldKeys(len(verts), func(i int) VertexModern { return verts[i] }, faces)
+	}
+	snapped := Mesh2Rgba{M.Header, verts, faces}
+	newMesh := snapped.filter(allTrue(len(faces)), keep)
+
+	report := QuantizeReport{VertsBefore: len(M.Verts), VertsAfter: len(newMesh.Verts)}
+	Q.report(&report)
+	finishReport(&report, M, newMesh)
+	return newMesh, &report
+}
+
+func (M *Mesh3) Quantize(opts QuantizeOptions) (*Mesh3, *QuantizeReport) {
+	verts := append([]VertexModern{}, M.Verts...)
+	faces := append([]Face{}, M.Faces...)
+	Q := newQuantizer(len(verts), func(i int) vertexAttributes { return verts[i].attributes() }, opts)
+	for i := range verts {
+		Q.snap(verts[i].attributes())
+	}
+
+	keep := allTrue(len(verts))
+	if opts.Weld {
+		keep = weldKeys(len(verts), func(i int) VertexModern { return verts[i] }, faces)
+	}
+	snapped := Mesh3{M.Header, verts, faces, M.Lods}
+	newMesh := snapped.filter(allTrue(len(faces)), keep)
+
+	report := QuantizeReport{VertsBefore: len(M.Verts), VertsAfter: len(newMesh.Verts)}
+	Q.report(&report)
+	finishReport(&report, M, newMesh)
+	return newMesh, &report
+}
+
+/* Verts only weld inside their own subset and with matching envelopes */
+func (M *Mesh4) Quantize(opts QuantizeOptions) (*Mesh4, *QuantizeReport) {
+	type weldKey struct {
+		vertex   VertexModern
+		envelope Envelope
+		subset   int
+	}
+
+	verts := append([]VertexModern{}, M.Verts...)
+	faces := append([]Face{}, M.Faces...)
+	Q := newQuantizer(len(verts), func(i int) vertexAttributes { return verts[i].attributes() }, opts)
+	for i := range verts {
+		Q.snap(verts[i].attributes())
+	}
+
+	keep := allTrue(len(verts))
+	if opts.Weld {
+		/* -1 keeps verts outside every subset from welding into subset 0 */
+		subsets := slices.Repeat([]int{-1}, len(verts))
+		for s, subset := range M.MeshSubsets {
+			for v := subset.VertsBegin; v < subset.VertsBegin+subset.VertsLength && v < uint32(len(verts)); v++ {
+				subsets[v] = s
+			}
+		}
+		keep = weldKeys(len(verts), func(i int) weldKey {
+			key := weldKey{vertex: verts[i], subset: subsets[i]}
+			if i < len(M.Envelopes) {
+				key.envelope = M.Envelopes[i]
+			}
+			return key
+		}, faces)
+	}
+	snapped := *M
+	snapped.Verts, snapped.Faces = verts, faces
+	newMesh := snapped.filter(allTrue(len(faces)), keep)
+
+	report := QuantizeReport{VertsBefore: len(M.Verts), VertsAfter: len(newMesh.Verts)}
+	Q.report(&report)
+	finishReport(&report, M, newMesh)
+	return newMesh, &report
+}
+
+func Quantize(mesh Mesh, opts QuantizeOptions) (Mesh, *QuantizeReport, error) {
+	switch typed := mesh.(type) {
+	case *Mesh2NoRgba:
+		newMesh, report := typed.Quantize(opts)
+		return newMesh, report, nil
+	case *Mesh2Rgba:
+		newMesh, report := typed.Quantize(opts)
+		return newMesh, report, nil
+	case *Mesh3:
+		newMesh, report := typed.Quantize(opts)
+		return newMesh, report, nil
+	case *Mesh4:
+		newMesh, report := typed.Quantize(opts)
+		return newMesh, report, nil
+	default:
+		return nil, nil, ErrBadMeshVersion
+	}
+}
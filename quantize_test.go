@@ -0,0 +1,60 @@
+package mesh_test
+
+import (
+	"github.com/MojaveMF/mesh"
+	"testing"
+)
+
+func TestQuantize(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+	original := mesh4.Verts[0]
+
+	quantized, report := mesh4.Quantize(mesh.QuantizeOptions{
+		PositionBits: 8,
+		NormalBits:   8,
+		UVBits:       8,
+		Weld:         true,
+	})
+	if mesh4.Verts[0] != original {
+		t.Error("source mesh was changed")
+	}
+	if report.Position.Max == 0 || report.Position.Max > 0.1 {
+		t.Errorf("unexpected position error %+v", report.Position)
+	}
+	if report.VertsAfter != len(quantized.Verts) || report.VertsAfter > report.VertsBefore {
+		t.Errorf("bad vert counts %d -> %d", report.VertsBefore, report.VertsAfter)
+	}
+	if len(report.Sizes) != 3 {
+		t.Errorf("expected 3 sizes got %d", len(report.Sizes))
+		return
+	}
+	for _, size := range report.Sizes {
+		if size.Saved() < 0 {
+			t.Errorf("quantizing grew version %d output %+v", size.Version, size)
+		}
+	}
+
+	/* Snapping a second time should not move anything */
+	_, again := quantized.Quantize(mesh.QuantizeOptions{PositionBits: 8})
+	if again.Position.Max > 1e-5 {
+		t.Errorf("already quantized mesh moved by %f", again.Position.Max)
+	}
+}
+
+func TestQuantizeWeldOutsideSubsets(t *testing.T) {
+	vertex := mesh.VertexModern{Px: 1, Py: 2, Pz: 3}
+	mesh4 := &mesh.Mesh4{
+		Verts:     []mesh.VertexModern{vertex, {Px: 4}, {Px: 5}, vertex},
+		Envelopes: make([]mesh.Envelope, 4),
+		Faces:     []mesh.Face{{A: 0, B: 1, C: 2}, {A: 3, B: 1, C: 2}},
+		Lods:      []uint32{0, 2},
+		MeshSubsets: []mesh.MeshSubset{
+			{FacesBegin: 0, FacesLength: 1, VertsBegin: 0, VertsLength: 3},
+		},
+	}
+
+	welded, _ := mesh4.Quantize(mesh.QuantizeOptions{Weld: true})
+	if len(welded.Verts) != 4 {
+		t.Errorf("vert outside every subset was welded into subset 0, %d verts left", len(welded.Verts))
+	}
+}
@@ -0,0 +1,356 @@
+package mesh
+
+import (
+	"fmt"
+	"math"
+)
+
+type RepairOptions struct {
+	/* Pull bad face indicies onto the last vertex instead of dropping the face */
+	ClampFaces bool
+}
+
+/* One change made by Repair, Index is -1 when it is about the whole section */
+type RepairAction struct {
+	Section Section
+	Index   int
+	Message string
+}
+
+func (A RepairAction) String() string {
+	return Problem(A).String()
+}
+
+type RepairLog []RepairAction
+
+func (L *RepairLog) add(section Section, index int, format string, args ...any) {
+	*L = append(*L, RepairAction{section, index, fmt.Sprintf(format, args...)})
+}
+
+func fixFloats(values ...*float32) bool {
+	fixed := false
+	for _, value := range values {
+		if math.IsNaN(float64(*value)) || math.IsInf(float64(*value), 0) {
+			*value = 0
+			fixed = true
+		}
+	}
+	return fixed
+}
+
+func (L *RepairLog) modernVerts(verts []VertexModern) {
+	for i := range verts {
+		V := &verts[i]
+		if fixFloats(&V.Px, &V.Py, &V.Pz, &V.Nx, &V.Ny, &V.Nz, &V.Tu, &V.Tv) {
+			L.add(SectionVertices, i, "replaced NaN or Inf with 0")
+		}
+	}
+}
+
+/*
+Returns which faces to keep, faces that are clamped are changed in place.
+Nothing is dropped when every face is fine.
+*/
+func (L *RepairLog) faces(faces []Face, numVerts int, opts RepairOptions) []bool {
+	var keep []bool
+	for i, face := range faces {
+		if faceInRange(face, numVerts) {
+			continue
+		}
+		if opts.ClampFaces && numVerts > 0 {
+			last := uint32(numVerts - 1)
+			faces[i] = Face{min(face.A, last), min(face.B, last), min(face.C, last)}
+			L.add(SectionFaces, i, "clamped %d,%d,%d to %d verts", face.A, face.B, face.C, numVerts)
+			continue
+		}
+		if keep == nil {
+			keep = allTrue(len(faces))
+		}
+		keep[i] = false
+		L.add(SectionFaces, i, "dropped face %d,%d,%d that points past %d verts", face.A, face.B, face.C, numVerts)
+	}
+	return keep
+}
+
+/* Broken lods turn into a single level holding every face */
+func (L *RepairLog) lods(lods []uint32, numFaces int) []uint32 {
+	broken := false
+	for i, lod := range lods {
+		if lod > uint32(numFaces) || (i > 0 && lod < lods[i-1]) {
+			broken = true
+		}
+	}
+	if !broken {
+		return lods
+	}
+	L.add(SectionLods, -1, "rebuilt lods %v as a single level of %d faces", lods, numFaces)
+	return []uint32{0, uint32(numFaces)}
+}
+
+func (L *RepairLog) count(name string, header *uint32, actual int) {
+	if *header != uint32(actual) {
+		L.add(SectionHeader, -1, "%s count %d set to %d", name, *header, actual)
+		*header = uint32(actual)
+	}
+}
+
+func (L *RepairLog) count16(name string, header *uint16, actual int) {
+	if *header != uint16(actual) {
+		L.add(SectionHeader, -1, "%s count %d set to %d", name, *header, actual)
+		*header = uint16(actual)
+	}
+}
+
+func (L *RepairLog) size8(name string, header *uint8, expected uint8) {
+	if *header != expected {
+		L.add(SectionHeader, -1, "%s size %d set to %d", name, *header, expected)
+		*header = expected
+	}
+}
+
+func (L *RepairLog) size16(name string, header *uint16, expected uint16) {
+	if *header != expected {
+		L.add(SectionHeader, -1, "%s size %d set to %d", name, *header, expected)
+		*header = expected
+	}
+}
+
+/* Repairs are made in place */
+func (M *Mesh1) Repair(opts RepairOptions) RepairLog {
+	log := RepairLog{}
+	if extra := len(M.Verts) % 3; extra != 0 {
+		log.add(SectionVertices, -1, "dropped %d verts that do not make a whole face", extra)
+		M.Verts = M.Verts[:len(M.Verts)-extra]
+	}
+	for i := range M.Verts {
+		V := &M.Verts[i]
+		if fixFloats(&V.Px, &V.Py, &V.Pz, &V.Nx, &V.Ny, &V.Nz, &V.Tu, &V.Tv) {
+			log.add(SectionVertices, i, "replaced NaN or Inf with 0")
+		}
+	}
+	log.count("face", &M.FaceCount, len(M.Verts)/3)
+	return log
+}
+
+func (M *Mesh2NoRgba) Repair(opts RepairOptions) RepairLog {
+	log := RepairLog{}
+	log.size16("header", &M.Header.MeshHeaderSize, Header2Size)
+	log.size8("vertex", &M.Header.VertexSize, VertexNoRgbaSize)
+	log.size8("face", &M.Header.FaceSize, FaceSize)
+
+	for i := range M.Verts {
+		V := &M.Verts[i]
+		if fixFloats(&V.Px, &V.Py, &V.Pz, &V.Nx, &V.Ny, &V.Nz, &V.Tu, &V.Tv) {
+			log.add(SectionVertices, i, "replaced NaN or Inf with 0")
+		}
+	}
+	if keep := log.faces(M.Faces, len(M.Verts), opts); keep != nil {
+		M.Faces = keepItems(M.Faces, keep)
+	}
+
+	log.count("vert", &M.Header.NumVerts, len(M.Verts))
+	log.count("face", &M.Header.NumFaces, len(M.Faces))
+	return log
+}
+
+func (M *Mesh2Rgba) Repair(opts RepairOptions) RepairLog {
+	log := RepairLog{}
+	log.size16("header", &M.Header.MeshHeaderSize, Header2Size)
+	log.size8("vertex", &M.Header.VertexSize, VertexModernSize)
+	log.size8("face", &M.Header.FaceSize, FaceSize)
+
+	log.modernVerts(M.Verts)
+	if keep := log.faces(M.Faces, len(M.Verts), opts); keep != nil {
+		M.Faces = keepItems(M.Faces, keep)
+	}
+
+	log.count("vert", &M.Header.NumVerts, len(M.Verts))
+	log.count("face", &M.Header.NumFaces, len(M.Faces))
+	return log
+}
+
+func (M *Mesh3) Repair(opts RepairOptions) RepairLog {
+	log := RepairLog{}
+	log.size16("header", &M.Header.MeshHeaderSize, Header3Size)
+	log.size8("vertex", &M.Header.VertexSize, VertexModernSize)
+	log.size8("face", &M.Header.FaceSize, FaceSize)
+	log.size16("lod", &M.Header.SizeofLod, 4)
+
+	log.modernVerts(M.Verts)
+	if keep := log.faces(M.Faces, len(M.Verts), opts); keep != nil {
+		M.Faces = keepItems(M.Faces, keep)
+		M.Lods = remapLods(M.Lods, prefixCount(keep))
+	}
+	M.Lods = log.lods(M.Lods, len(M.Faces))
+
+	log.count("vert", &M.Header.NumVerts, len(M.Verts))
+	log.count("face", &M.Header.NumFaces, len(M.Faces))
+	log.count16("lod", &M.Header.NumLods, len(M.Lods))
+	return log
+}
+
+func (M *Mesh4) Repair(opts RepairOptions) RepairLog {
+	log := RepairLog{}
+	log.size16("header", &M.Header.SizeOf_MeshHeader, Header4Size)
+
+	log.modernVerts(M.Verts)
+	if len(M.Bones) > 0 && len(M.Envelopes) != len(M.Verts) {
+		log.add(SectionEnvelopes, -1, "resized %d envelopes to match %d verts", len(M.Envelopes), len(M.Verts))
+		envelopes := make([]Envelope, len(M.Verts))
+		copy(envelopes, M.Envelopes)
+		M.Envelopes = envelopes
+	}
+
+	if keep := log.faces(M.Faces, len(M.Verts), opts); keep != nil {
+		prefix := prefixCount(keep)
+		M.Faces = keepItems(M.Faces, keep)
+		M.Lods = remapLods(M.Lods, prefix)
+		for i := range M.MeshSubsets {
+			subset := &M.MeshSubsets[i]
+			subset.FacesBegin, subset.FacesLength = remapRange(prefix, subset.FacesBegin, subset.FacesLength)
+		}
+	}
+	M.Lods = log.lods(M.Lods, len(M.Faces))
+
+	log.subsets(M)
+	log.bones(M)
+	log.names(M)
+	log.envelopes(M)
+	log.weights(M)
+
+	log.count("vert", &M.Header.NumVerts, len(M.Verts))
+	log.count("face", &M.Header.NumFaces, len(M.Faces))
+	log.count16("lod", &M.Header.NumLods, len(M.Lods))
+	log.count16("bone", &M.Header.NumBones, len(M.Bones))
+	log.count("name table byte", &M.Header.SizeOf_bone_names_Buffer, len(M.NameTable))
+	log.count16("subset", &M.Header.NumSubsets, len(M.MeshSubsets))
+	return log
+}
+
+func (L *RepairLog) subsets(M *Mesh4) {
+	for i := range M.MeshSubsets {
+		subset := &M.MeshSubsets[i]
+		if end := uint64(subset.FacesBegin) + uint64(subset.FacesLength); end > uint64(len(M.Faces)) {
+			begin := min(subset.FacesBegin, uint32(len(M.Faces)))
+			L.add(SectionSubsets, i, "clamped faces %d+%d to %d faces", subset.FacesBegin, subset.FacesLength, len(M.Faces))
+			subset.FacesBegin, subset.FacesLength = begin, uint32(len(M.Faces))-begin
+		}
+		if end := uint64(subset.VertsBegin) + uint64(subset.VertsLength); end > uint64(len(M.Verts)) {
+			begin := min(subset.VertsBegin, uint32(len(M.Verts)))
+			L.add(SectionSubsets, i, "clamped verts %d+%d to %d verts", subset.VertsBegin, subset.VertsLength, len(M.Verts))
+			subset.VertsBegin, subset.VertsLength = begin, uint32(len(M.Verts))-begin
+		}
+		if subset.NumBonesIndicies > MaxSubsetBones {
+			L.add(SectionSubsets, i, "clamped %d bones to %d", subset.NumBonesIndicies, MaxSubsetBones)
+			subset.NumBonesIndicies = MaxSubsetBones
+		}
+	}
+}
+
+/* Bad parents become roots, each cycle is cut at its lowest bone */
+func (L *RepairLog) bones(M *Mesh4) {
+	for i := range M.Bones {
+		bone := &M.Bones[i]
+		if bone.ParentIndex != NoBone && int(bone.ParentIndex) >= len(M.Bones) {
+			L.add(SectionBones, i, "parent %d does not exist, made it a root", bone.ParentIndex)
+			bone.ParentIndex = NoBone
+		}
+		if bone.LodParentIndex != NoBone && int(bone.LodParentIndex) >= len(M.Bones) {
+			L.add(SectionBones, i, "lod parent %d does not exist, made it a root", bone.LodParentIndex)
+			bone.LodParentIndex = NoBone
+		}
+		if fixFloats(&bone.Culling, &bone.R00, &bone.R01, &bone.R02, &bone.R10, &bone.R11, &bone.R12, &bone.R20, &bone.R21, &bone.R22, &bone.X, &bone.Y, &bone.Z) {
+			L.add(SectionBones, i, "replaced NaN or Inf with 0")
+		}
+	}
+	for _, cycle := range boneCycles(M.Bones) {
+		L.add(SectionBones, cycle[0], "broke a parent cycle of %d bones by making it a root", len(cycle))
+		M.Bones[cycle[0]].ParentIndex = NoBone
+	}
+}
+
+/* Rebuilds the table when any bone has no readable name, good names are kept */
+func (L *RepairLog) names(M *Mesh4) {
+	if len(M.Bones) == 0 {
+		return
+	}
+	broken := false
+	for _, bone := range M.Bones {
+		if bone.BoneNameIndex >= uint32(len(M.NameTable)) || !containsByte(M.NameTable[bone.BoneNameIndex:], 0) {
+			broken = true
+		}
+	}
+	if !broken {
+		return
+	}
+
+	table := make([]byte, 0, len(M.NameTable))
+	used := make(map[string]bool, len(M.Bones))
+	for i := range M.Bones {
+		name := ""
+		if start := M.Bones[i].BoneNameIndex; start < uint32(len(M.NameTable)) && containsByte(M.NameTable[start:], 0) {
+			name = M.BoneName(i)
+		}
+		if name == "" || used[name] {
+			name = fmt.Sprintf("Bone%d", i)
+			L.add(SectionNames, i, "named bone %q", name)
+		}
+		used[name] = true
+		M.Bones[i].BoneNameIndex = uint32(len(table))
+		table = append(table, name...)
+		table = append(table, 0)
+	}
+	L.add(SectionNames, -1, "rebuilt name table of %d bytes as %d bytes", len(M.NameTable), len(table))
+	M.NameTable = table
+}
+
+/* Slots pointing past the bones they can use are emptied, weights renormalize after */
+func (L *RepairLog) envelopes(M *Mesh4) {
+	if len(M.Bones) == 0 {
+		return
+	}
+	owner := envelopeOwners(M)
+	for i := range M.Envelopes {
+		envelope := &M.Envelopes[i]
+		limit := uint32(len(M.Bones))
+		if len(M.MeshSubsets) > 0 {
+			limit = 0
+			if owner[i] >= 0 {
+				limit = min(M.MeshSubsets[owner[i]].NumBonesIndicies, MaxSubsetBones)
+			}
+		}
+		for slot := 0; slot < 4; slot++ {
+			if envelope.Weights[slot] == 0 || uint32(envelope.Bones[slot]) < limit {
+				continue
+			}
+			L.add(SectionEnvelopes, i, "cleared slot %d, bone %d is past the %d it can use", slot, envelope.Bones[slot], limit)
+			envelope.Bones[slot], envelope.Weights[slot] = 0, 0
+		}
+	}
+}
+
+/* Weights have to add up to 255, the rounding error goes to the heaviest bone */
+func (L *RepairLog) weights(M *Mesh4) {
+	if len(M.Bones) == 0 {
+		return
+	}
+	for i := range M.Envelopes {
+		weights := &M.Envelopes[i].Weights
+		total := int(weights[0]) + int(weights[1]) + int(weights[2]) + int(weights[3])
+		if total == 255 || total == 0 {
+			continue
+		}
+
+		before := *weights
+		sum, heaviest := 0, 0
+		for slot := range weights {
+			weights[slot] = byte(int(before[slot]) * 255 / total)
+			sum += int(weights[slot])
+			if before[slot] > before[heaviest] {
+				heaviest = slot
+			}
+		}
+		weights[heaviest] += byte(255 - sum)
+		L.add(SectionEnvelopes, i, "weights %v summed to %d, now %v", before, total, *weights)
+	}
+}
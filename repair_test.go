@@ -0,0 +1,71 @@
+package mesh_test
+
+import (
+	"github.com/MojaveMF/mesh"
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+	if log := mesh4.Repair(mesh.RepairOptions{}); len(log) != 0 {
+		t.Errorf("valid mesh was changed %v", log)
+	}
+
+	mesh4.Faces[0].B = 99999
+	mesh4.Lods[2] = 1
+	mesh4.Bones[0].ParentIndex = 4
+	mesh4.Bones[2].ParentIndex = 700
+	mesh4.NameTable = []byte{}
+	mesh4.Envelopes[5].Weights = [4]byte{100, 50, 0, 0}
+	mesh4.Header.NumVerts = 3
+
+	log := mesh4.Repair(mesh.RepairOptions{})
+	if len(log) == 0 {
+		t.Error("nothing was repaired")
+	}
+	if err := mesh4.Validate().Err(); err != nil {
+		t.Errorf("mesh still broken after repair: %s", err)
+	}
+	if len(mesh4.Faces) != 6327 || len(mesh4.Lods) != 2 {
+		t.Errorf("got %d faces lods %v", len(mesh4.Faces), mesh4.Lods)
+	}
+	if mesh4.Envelopes[5].Weights != [4]byte{170, 85, 0, 0} {
+		t.Errorf("weights not renormalized %v", mesh4.Envelopes[5].Weights)
+	}
+	if mesh4.BoneName(1) != "Bone1" {
+		t.Errorf("names not regenerated, got %q", mesh4.BoneName(1))
+	}
+}
+
+func TestRepairClamp(t *testing.T) {
+	broken := twoTriangles()
+	broken.Faces[1].C = 50
+
+	log := broken.Repair(mesh.RepairOptions{ClampFaces: true})
+	if len(log) != 1 || log[0].Section != mesh.SectionFaces || broken.Faces[1].C != 5 {
+		t.Errorf("face not clamped %v %+v", log, broken.Faces[1])
+	}
+}
+
+func TestRepairEnvelopeBones(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+	subset := mesh4.MeshSubsets[0]
+	mesh4.Envelopes[7] = mesh.Envelope{Bones: [4]byte{0, byte(subset.NumBonesIndicies) + 2, 0, 0}, Weights: [4]byte{155, 100, 0, 0}}
+	mesh4.Envelopes[8] = mesh.Envelope{Bones: [4]byte{200, 0, 0, 0}, Weights: [4]byte{255, 0, 0, 0}}
+	if mesh4.Validate().Err() == nil {
+		t.Fatal("bad envelope bones were not reported")
+	}
+
+	if log := mesh4.Repair(mesh.RepairOptions{}); len(log) == 0 {
+		t.Error("nothing was repaired")
+	}
+	if problems := mesh4.Validate(); len(problems) != 0 {
+		t.Errorf("mesh still broken after repair: %v", problems)
+	}
+	if mesh4.Envelopes[7].Weights != [4]byte{255, 0, 0, 0} {
+		t.Errorf("weights not renormalized %v", mesh4.Envelopes[7].Weights)
+	}
+	if mesh4.Envelopes[8].Weights != [4]byte{} {
+		t.Errorf("slot past the subset bones kept its weight %v", mesh4.Envelopes[8])
+	}
+}
@@ -0,0 +1,111 @@
+package mesh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+)
+
+/* Version line, the biggest header and a v1 face count line all fit in here */
+const sniffSize = 64
+
+/* What the start of a mesh says about the rest of it */
+type MeshInfo struct {
+	Version uint8
+
+	/* *MeshHeader2, *MeshHeader3 or *MeshHeader4, nil for v1 and registered versions with their own layout */
+	Header any
+
+	NumVerts   uint32
+	NumFaces   uint32
+	NumLods    uint16
+	NumBones   uint16
+	NumSubsets uint16
+
+	/* Whole file including the version line, -1 for v1 since it is text and for registered versions with their own layout */
+	Size int64
+}
+
+/*
+Looks at the mesh waiting in reader without taking anything out of it,
+so the same reader can be handed to DecodeMesh afterwards.
+*/
+func Peek(reader *bufio.Reader) (*MeshInfo, error) {
+	prefix, err := reader.Peek(sniffSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	return sniff(prefix)
+}
+
+/* Same as Peek for seekable input, the stream is put back where it was */
+func Sniff(stream io.ReadSeeker) (*MeshInfo, error) {
+	start, err := stream.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, sniffSize)
+	read, err := io.ReadFull(stream, prefix)
+	if _, seekErr := stream.Seek(start, io.SeekStart); seekErr != nil {
+		return nil, seekErr
+	}
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return sniff(prefix[:read])
+}
+
+func sniff(prefix []byte) (*MeshInfo, error) {
+	reader := bytes.NewReader(prefix)
+	version, err := MeshVersion(reader)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	/* Registered versions with a built in layout are read like it */
+	info := MeshInfo{Version: version, Size: -1}
+	lineSize := int64(len(versionLine(version)))
+	switch versionFamily(version) {
+	case MeshVersion1:
+		line, err := ReadLine(reader)
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		faceCount, err := strconv.ParseUint(line, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		info.NumFaces = uint32(faceCount)
+		info.NumVerts = uint32(min(faceCount*3, uint64(^uint32(0))))
+	case MeshVersion2:
+		header := MeshHeader2{}
+		if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		info.Header, info.NumVerts, info.NumFaces = &header, header.NumVerts, header.NumFaces
+		info.Size = lineSize + int64(Header2Size) + int64(header.bodySize())
+	case MeshVersion3:
+		header := MeshHeader3{}
+		if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		info.Header, info.NumVerts, info.NumFaces = &header, header.NumVerts, header.NumFaces
+		info.NumLods = header.NumLods
+		info.Size = lineSize + int64(Header3Size) + int64(header.bodySize())
+	case MeshVersion4:
+		header := MeshHeader4{}
+		if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		info.Header, info.NumVerts, info.NumFaces = &header, header.NumVerts, header.NumFaces
+		info.NumLods, info.NumBones, info.NumSubsets = header.NumLods, header.NumBones, header.NumSubsets
+		info.Size = lineSize + int64(Header4Size) + int64(header.bodySize())
+	}
+	return &info, nil
+}
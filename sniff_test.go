@@ -0,0 +1,89 @@
+package mesh_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/MojaveMF/mesh"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestPeek(t *testing.T) {
+	for _, path := range []string{"./testdata/output.v2", "./testdata/output.v3", "./testdata/output.v4"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader := bufio.NewReader(bytes.NewReader(data))
+		info, err := mesh.Peek(reader)
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		if info.Size != int64(len(data)) {
+			t.Errorf("%s: expected size %d got %d", path, len(data), info.Size)
+		}
+
+		/* Nothing was taken so decoding still works */
+		decoded, err := mesh.DecodeMesh(reader)
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		if !reflect.DeepEqual(decoded, decodeFile(t, path)) {
+			t.Errorf("%s: decode after peek differs", path)
+		}
+	}
+}
+
+func TestSniff(t *testing.T) {
+	file, err := os.Open("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	info, err := mesh.Sniff(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := info.Header.(*mesh.MeshHeader4)
+	if info.Version != mesh.MeshVersion4 || info.NumFaces != header.NumFaces || info.NumBones != header.NumBones || info.NumLods != 6 {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if position, _ := file.Seek(0, io.SeekCurrent); position != 0 {
+		t.Errorf("sniff left the file at %d", position)
+	}
+}
+
+func TestSniffV1(t *testing.T) {
+	info, err := mesh.Sniff(bytes.NewReader([]byte("version 1.00\n2\n[0,0,0]")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.NumFaces != 2 || info.NumVerts != 6 || info.Size != -1 || info.Header != nil {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestSniffShort(t *testing.T) {
+	for _, data := range []string{"", "version 4.0", "version 4.00\n\x18\x00"} {
+		if _, err := mesh.Sniff(bytes.NewReader([]byte(data))); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%q: expected unexpected EOF got %v", data, err)
+		}
+	}
+	if _, err := mesh.Sniff(bytes.NewReader([]byte("version 9.00\n"))); !errors.Is(err, mesh.ErrUnkownMeshVersion) {
+		t.Errorf("expected unknown version got %v", err)
+	}
+}
+
+func TestReadLineAllocations(t *testing.T) {
+	data := []byte("version 4.00\n")
+	allocations := testing.AllocsPerRun(20, func() {
+		mesh.ReadLine(bytes.NewReader(data))
+	})
+	if allocations > 3 {
+		t.Errorf("ReadLine made %.0f allocations", allocations)
+	}
+}
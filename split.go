@@ -0,0 +1,178 @@
+package mesh
+
+/*
+Splitting keeps the source type, every piece only holds the verts its faces
+use. Lods stay as lods, a piece with nothing in a level just has an empty
+level. Mesh4 pieces keep the whole skeleton so envelopes still line up.
+*/
+
+type unionFind []int32
+
+func newUnionFind(size int) unionFind {
+	parents := make(unionFind, size)
+	for i := range parents {
+		parents[i] = int32(i)
+	}
+	return parents
+}
+
+func (U unionFind) find(i int32) int32 {
+	for U[i] != i {
+		U[i] = U[U[i]]
+		i = U[i]
+	}
+	return i
+}
+
+func (U unionFind) union(a, b int32) {
+	rootA, rootB := U.find(a), U.find(b)
+	if rootA == rootB {
+		return
+	}
+	/* Lowest index wins so component order follows vert order */
+	if rootA < rootB {
+		U[rootB] = rootA
+	} else {
+		U[rootA] = rootB
+	}
+}
+
+/*
+Labels every face with its component, faces that point outside the vert list
+get -1. When weld is set verts in the same spot count as connected.
+*/
+func faceComponents(numVerts int, faces []Face, weld bool, position func(int) [3]float32) ([]int, int) {
+	verts := newUnionFind(numVerts)
+	for _, face := range faces {
+		if !faceInRange(face, numVerts) {
+			continue
+		}
+		verts.union(int32(face.A), int32(face.B))
+		verts.union(int32(face.A), int32(face.C))
+	}
+	if weld {
+		welded := make(map[[3]float32]int32, numVerts)
+		for i := 0; i < numVerts; i++ {
+			key := position(i)
+			if first, ok := welded[key]; ok {
+				verts.union(first, int32(i))
+			} else {
+				welded[key] = int32(i)
+			}
+		}
+	}
+
+	labels := make([]int, len(faces))
+	rootLabels := make(map[int32]int)
+	for i, face := range faces {
+		if !faceInRange(face, numVerts) {
+			labels[i] = -1
+			continue
+		}
+		root := verts.find(int32(face.A))
+		label, ok := rootLabels[root]
+		if !ok {
+			label = len(rootLabels)
+			rootLabels[root] = label
+		}
+		labels[i] = label
+	}
+	return labels, len(rootLabels)
+}
+
+/* Faces are bucketed by label in one pass so each piece only touches its own faces */
+func splitLabels(numVerts int, faces []Face, labels []int, count int, split func(faces, verts []uint32)) {
+	buckets := make([][]uint32, count)
+	for i, label := range labels {
+		if label >= 0 {
+			buckets[label] = append(buckets[label], uint32(i))
+		}
+	}
+	for _, bucket := range buckets {
+		split(bucket, pickedVerts(numVerts, faces, bucket))
+	}
+}
+
+func (M *Mesh2NoRgba) SplitComponents(weld bool) []*Mesh2NoRgba {
+	labels, count := faceComponents(len(M.Verts), M.Faces, weld, func(i int) [3]float32 {
+		return [3]float32{M.Verts[i].Px, M.Verts[i].Py, M.Verts[i].Pz}
+	})
+	pieces := make([]*Mesh2NoRgba, 0, count)
+	splitLabels(len(M.Verts), M.Faces, labels, count, func(faces, verts []uint32) {
+		pieces = append(pieces, M.pick(faces, verts))
+	})
+	return pieces
+}
+
+func (M *Mesh2Rgba) SplitComponents(weld bool) []*Mesh2Rgba {
+	labels, count := faceComponents(len(M.Verts), M.Faces, weld, func(i int) [3]float32 {
+		return [3]float32{M.Verts[i].Px, M.Verts[i].Py, M.Verts[i].Pz}
+	})
+	pieces := make([]*Mesh2Rgba, 0, count)
+	splitLabels(len(M.Verts), M.Faces, labels, count, func(faces, verts []uint32) {
+		pieces = append(pieces, M.pick(faces, verts))
+	})
+	return pieces
+}
+
+func (M *Mesh3) SplitComponents(weld bool) []*Mesh3 {
+	labels, count := faceComponents(len(M.Verts), M.Faces, weld, func(i int) [3]float32 {
+		return [3]float32{M.Verts[i].Px, M.Verts[i].Py, M.Verts[i].Pz}
+	})
+	pieces := make([]*Mesh3, 0, count)
+	splitLabels(len(M.Verts), M.Faces, labels, count, func(faces, verts []uint32) {
+		pieces = append(pieces, M.pick(faces, verts))
+	})
+	return pieces
+}
+
+func (M *Mesh4) SplitComponents(weld bool) []*Mesh4 {
+	labels, count := faceComponents(len(M.Verts), M.Faces, weld, func(i int) [3]float32 {
+		return [3]float32{M.Verts[i].Px, M.Verts[i].Py, M.Verts[i].Pz}
+	})
+	pieces := make([]*Mesh4, 0, count)
+	splitLabels(len(M.Verts), M.Faces, labels, count, func(faces, verts []uint32) {
+		pieces = append(pieces, M.pick(faces, verts))
+	})
+	return pieces
+}
+
+/* Faces in every lod follow the subset whose vert range they use */
+func (M *Mesh4) SplitSubsets() []*Mesh4 {
+	pieces := make([]*Mesh4, 0, len(M.MeshSubsets))
+	for _, subset := range M.MeshSubsets {
+		begin, end := subset.VertsBegin, subset.VertsBegin+subset.VertsLength
+		keepFace := make([]bool, len(M.Faces))
+		for i, face := range M.Faces {
+			keepFace[i] = face.A >= begin && face.A < end
+		}
+		pieces = append(pieces, M.filter(keepFace, usedVerts(len(M.Verts), M.Faces, keepFace)))
+	}
+	return pieces
+}
+
+/* Convenience for callers holding the interface, v1 meshes cant be split */
+func SplitComponents(mesh Mesh, weld bool) ([]Mesh, error) {
+	pieces := make([]Mesh, 0)
+	switch typed := mesh.(type) {
+	case *Mesh2NoRgba:
+		for _, piece := range typed.SplitComponents(weld) {
+			pieces = append(pieces, piece)
+		}
+	case *Mesh2Rgba:
+		for _, piece := range typed.SplitComponents(weld) {
+			pieces = append(pieces, piece)
+		}
+	case *Mesh3:
+		for _, piece := range typed.SplitComponents(weld) {
+			pieces = append(pieces, piece)
+		}
+	case *Mesh4:
+		for _, piece := range typed.SplitComponents(weld) {
+			pieces = append(pieces, piece)
+		}
+	default:
+		return nil, ErrBadMeshVersion
+	}
+	return pieces, nil
+}
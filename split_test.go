@@ -0,0 +1,105 @@
+package mesh_test
+
+import (
+	"github.com/MojaveMF/mesh"
+	"slices"
+	"testing"
+)
+
+func twoTriangles() *mesh.Mesh3 {
+	verts := []mesh.VertexModern{
+		{Px: 0}, {Px: 1}, {Py: 1},
+		{Px: 1}, {Px: 2}, {Px: 2, Py: 1},
+	}
+	faces := []mesh.Face{{A: 0, B: 1, C: 2}, {A: 3, B: 4, C: 5}}
+	return &mesh.Mesh3{
+		Header: mesh.MeshHeader3{
+			MeshHeaderSize: mesh.Header3Size,
+			VertexSize:     mesh.VertexModernSize,
+			FaceSize:       mesh.FaceSize,
+			SizeofLod:      4,
+			NumLods:        2,
+			NumVerts:       uint32(len(verts)),
+			NumFaces:       uint32(len(faces)),
+		},
+		Verts: verts,
+		Faces: faces,
+		Lods:  []uint32{0, 2},
+	}
+}
+
+func TestSplitComponents(t *testing.T) {
+	pieces := twoTriangles().SplitComponents(false)
+	if len(pieces) != 2 {
+		t.Errorf("expected 2 pieces got %d", len(pieces))
+		return
+	}
+	second := pieces[1]
+	if len(second.Verts) != 3 || second.Header.NumVerts != 3 {
+		t.Errorf("piece was not compacted %+v", second.Header)
+	}
+	if second.Faces[0] != (mesh.Face{A: 0, B: 1, C: 2}) || second.Verts[0].Px != 1 {
+		t.Errorf("piece was not reindexed %+v", second.Faces)
+	}
+	if second.Lods[1] != 1 {
+		t.Errorf("lods not remapped %v", second.Lods)
+	}
+
+	/* Vert 1 and 3 share a position */
+	if welded := twoTriangles().SplitComponents(true); len(welded) != 1 {
+		t.Errorf("expected welded mesh to be one piece got %d", len(welded))
+	}
+}
+
+func TestSplitManyComponents(t *testing.T) {
+	/* Separate triangles, the first half in lod 0 and the rest in lod 1 */
+	many := twoTriangles()
+	many.Verts, many.Faces = nil, nil
+	for i := range 2000 {
+		x := float32(i * 10)
+		many.Verts = append(many.Verts, mesh.VertexModern{Px: x}, mesh.VertexModern{Px: x + 1}, mesh.VertexModern{Px: x, Py: 1})
+		many.Faces = append(many.Faces, mesh.Face{A: uint32(i * 3), B: uint32(i*3 + 1), C: uint32(i*3 + 2)})
+	}
+	many.Lods = []uint32{0, 1000, 2000}
+
+	pieces := many.SplitComponents(false)
+	if len(pieces) != 2000 {
+		t.Fatalf("expected 2000 pieces got %d", len(pieces))
+	}
+	for i, piece := range pieces {
+		lods := []uint32{0, 0, 1}
+		if i < 1000 {
+			lods = []uint32{0, 1, 1}
+		}
+		if len(piece.Verts) != 3 || piece.Verts[0].Px != float32(i*10) || piece.Faces[0] != (mesh.Face{A: 0, B: 1, C: 2}) || !slices.Equal(piece.Lods, lods) {
+			t.Fatalf("piece %d: got %d verts faces %v lods %v", i, len(piece.Verts), piece.Faces, piece.Lods)
+		}
+	}
+}
+
+func TestSplitSubsets(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+	pieces := mesh4.SplitSubsets()
+	if len(pieces) != len(mesh4.MeshSubsets) {
+		t.Errorf("expected %d pieces got %d", len(mesh4.MeshSubsets), len(pieces))
+		return
+	}
+	piece := pieces[0]
+	if len(piece.Bones) != len(mesh4.Bones) || len(piece.Envelopes) != len(piece.Verts) {
+		t.Error("skinning was not kept")
+	}
+	if piece.MeshSubsets[0].FacesLength != mesh4.MeshSubsets[0].FacesLength {
+		t.Errorf("subset faces changed %+v", piece.MeshSubsets[0])
+	}
+
+	components, err := mesh.SplitComponents(mesh4, true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, component := range components {
+		if len(component.(*mesh.Mesh4).Faces) == 0 {
+			t.Error("empty component")
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package mesh
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+/* How many items go through a transcode at once, keeps memory flat for any mesh size */
+const transcodeChunk = 1024
+
+/*
+Converts the mesh in src to the target version and writes it to dst without
+holding the mesh in memory. Output matches decoding then an Encoder set to
+the same version.
+
+Going down to v2 needs the LOD 0 face count which is stored after the faces,
+so when src cant seek (or the decode is lenient) the mesh is fully decoded.
+On errors dst may already have part of the mesh written to it.
+*/
+func Transcode(src io.Reader, dst io.Writer, target uint8) error {
+	return TranscodeOptions(src, dst, target, DecodeOptions{Limits: DefaultLimits})
+}
+
+func TranscodeOptions(src io.Reader, dst io.Writer, target uint8, opts DecodeOptions) error {
+	reader := newMeshReader(src, opts, 0)
+	start := reader.position()
+	version, err := MeshVersion(reader)
+	if err != nil {
+		return &DecodeError{0, SectionHeader, -1, start, err}
+	}
+	reader.version = version
+	_, err = transcode(reader, version, target, dst)
+	return err
+}
+
+/* Collapses the versions that share a layout, registered ones go by their Layout */
+func versionFamily(version uint8) uint8 {
+	switch version {
+	case MeshVersion1, MeshVersion1_01:
+		return MeshVersion1
+	case MeshVersion2:
+		return MeshVersion2
+	case MeshVersion3, MeshVersion3_01:
+		return MeshVersion3
+	case MeshVersion4, MeshVersion4_1:
+		return MeshVersion4
+	}
+	codec, ok := codecForVersion(version)
+	if !ok || !builtinVersion(codec.Layout) {
+		return 0
+	}
+	return versionFamily(codec.Layout)
+}
+
+func (R *meshReader) seekable() bool {
+	_, ok := R.stream.(io.Seeker)
+	return ok && R.remaining >= 0
+}
+
+/* The version line was already read from R, reports whether it had to fall back to decoding */
+func transcode(R *meshReader, version uint8, target uint8, dst io.Writer) (Strategy, error) {
+	if !writable(target) {
+		return StrategyReencode, ErrBadMeshVersion
+	}
+	from, to := versionFamily(version), versionFamily(target)
+	if from == MeshVersion1 || from == 0 || to == MeshVersion1 || to == 0 || R.opts.Lenient || (to == MeshVersion2 && from != MeshVersion2 && !R.seekable()) {
+		return StrategyReencode, reencode(R, version, target, 0, dst)
+	}
+
+	switch from {
+	case MeshVersion2:
+		return StrategyStreaming, R.transcode2(target, dst)
+	case MeshVersion3:
+		return StrategyStreaming, R.transcode3(target, dst)
+	default:
+		return StrategyStreaming, R.transcode4(target, dst)
+	}
+}
+
+func versionLine(version uint8) string {
+	line, _ := MeshHeader(version)
+	return line + "\n"
+}
+
+func writeHeader(dst io.Writer, versionLine string, header any) error {
+	if _, err := io.WriteString(dst, versionLine); err != nil {
+		return err
+	}
+	return binary.Write(dst, binary.LittleEndian, header)
+}
+
+/*
+Streams count items of size bytes from R to dst a chunk at a time, rewrite
+can change each chunk on the way through. Read errors point at the item
+that was cut short.
+*/
+func (R *meshReader) pipeSection(dst io.Writer, section Section, count uint64, size int, rewrite func([]byte) []byte) error {
+	if count == 0 {
+		return nil
+	}
+	chunk := make([]byte, min(count, transcodeChunk)*uint64(size))
+	for done := uint64(0); done < count; {
+		items := min(count-done, transcodeChunk)
+		start := R.position()
+		data := chunk[:items*uint64(size)]
+		if read, err := io.ReadFull(R, data); err != nil {
+			whole := read / size
+			return R.fail(section, int(done)+whole, start+int64(whole*size), err)
+		}
+		if rewrite != nil {
+			data = rewrite(data)
+		}
+		if _, err := dst.Write(data); err != nil {
+			return err
+		}
+		done += items
+	}
+	return nil
+}
+
+/* Same as VertexNoRgba.Modern, the first 36 bytes line up so only the color is added */
+func noRgbaToModern() func([]byte) []byte {
+	var converted []byte
+	return func(data []byte) []byte {
+		converted = converted[:0]
+		for len(data) >= int(VertexNoRgbaSize) {
+			converted = append(converted, data[:VertexNoRgbaSize]...)
+			converted = append(converted, DefaultColor.R, DefaultColor.G, DefaultColor.B, DefaultColor.A)
+			data = data[VertexNoRgbaSize:]
+		}
+		return converted
+	}
+}
+
+/* Where LOD 1 starts without reading the sections before it, src is put back after */
+func (R *meshReader) peekLod0(skip uint64, numLods uint16, numFaces uint32) (uint32, error) {
+	if numLods < 2 {
+		return numFaces, nil
+	}
+	seeker := R.stream.(io.Seeker)
+	current, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	offset := R.position() + int64(skip) + 4
+	if _, err := seeker.Seek(int64(skip)+4, io.SeekCurrent); err != nil {
+		return 0, err
+	}
+	lod := [4]byte{}
+	_, err = io.ReadFull(R.stream, lod[:])
+	if _, seekErr := seeker.Seek(current, io.SeekStart); seekErr != nil {
+		return 0, seekErr
+	}
+	if err != nil {
+		return 0, R.fail(SectionLods, 1, offset, err)
+	}
+
+	/* Same as GetNormalFaces */
+	if lod0 := binary.LittleEndian.Uint32(lod[:]); lod0 <= numFaces {
+		return lod0, nil
+	}
+	return numFaces, nil
+}
+
+func (R *meshReader) transcode2(target uint8, dst io.Writer) error {
+	to, line := versionFamily(target), versionLine(target)
+	header := MeshHeader2{}
+	if err := R.readHeader(&header); err != nil {
+		return err
+	}
+	if err := R.checkHeader2(&header); err != nil {
+		return R.fail(SectionHeader, -1, R.position(), err)
+	}
+
+	noRgba := header.VertexSize == 36
+	vertexSize := int(VertexModernSize)
+	var rewrite func([]byte) []byte
+	if noRgba {
+		vertexSize = int(VertexNoRgbaSize)
+		if to != MeshVersion2 {
+			rewrite = noRgbaToModern()
+		}
+	}
+
+	/* v2 meshes get a single lod level when exported */
+	lods := singleLod(header.NumFaces)
+
+	var err error
+	switch to {
+	case MeshVersion2:
+		err = writeHeader(dst, line, header)
+	case MeshVersion3:
+		err = writeHeader(dst, line, MeshHeader3{
+			Header3Size, VertexModernSize, FaceSize, 4,
+			uint16(len(lods)), header.NumVerts, header.NumFaces,
+		})
+	case MeshVersion4:
+		err = writeHeader(dst, line, MeshHeader4{
+			SizeOf_MeshHeader: Header4Size,
+			NumVerts:          header.NumVerts,
+			NumFaces:          header.NumFaces,
+			NumLods:           ushort(len(lods)),
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := R.pipeSection(dst, SectionVertices, uint64(header.NumVerts), vertexSize, rewrite); err != nil {
+		return err
+	}
+	if err := R.pipeSection(dst, SectionFaces, uint64(header.NumFaces), int(FaceSize), nil); err != nil {
+		return err
+	}
+	if to == MeshVersion2 {
+		return nil
+	}
+	return writeSection(dst, lods, len(lods))
+}
+
+func (R *meshReader) transcode3(target uint8, dst io.Writer) error {
+	to, line := versionFamily(target), versionLine(target)
+	header := MeshHeader3{}
+	if err := R.readHeader(&header); err != nil {
+		return err
+	}
+	if err := R.checkHeader3(&header); err != nil {
+		return R.fail(SectionHeader, -1, R.position(), err)
+	}
+
+	/* Down to v2 only LOD 0 is kept and the lods are never written */
+	faces, lodsTo := header.NumFaces, dst
+	var err error
+	switch to {
+	case MeshVersion2:
+		skip := uint64(header.NumVerts)*uint64(VertexModernSize) + uint64(header.NumFaces)*uint64(FaceSize)
+		if faces, err = R.peekLod0(skip, header.NumLods, header.NumFaces); err != nil {
+			return err
+		}
+		lodsTo = io.Discard
+		err = writeHeader(dst, line, MeshHeader2{
+			Header2Size, VertexModernSize, FaceSize, header.NumVerts, faces,
+		})
+	case MeshVersion3:
+		err = writeHeader(dst, line, header)
+	case MeshVersion4:
+		err = writeHeader(dst, line, MeshHeader4{
+			SizeOf_MeshHeader: Header4Size,
+			NumVerts:          header.NumVerts,
+			NumFaces:          header.NumFaces,
+			NumLods:           header.NumLods,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := R.pipeSection(dst, SectionVertices, uint64(header.NumVerts), int(VertexModernSize), nil); err != nil {
+		return err
+	}
+	if err := R.transcodeFaces(dst, faces, header.NumFaces); err != nil {
+		return err
+	}
+	return R.pipeSection(lodsTo, SectionLods, uint64(header.NumLods), 4, nil)
+}
+
+func (R *meshReader) transcode4(target uint8, dst io.Writer) error {
+	to, line := versionFamily(target), versionLine(target)
+	header := MeshHeader4{}
+	if err := R.readHeader(&header); err != nil {
+		return err
+	}
+	if err := R.checkHeader4(&header); err != nil {
+		return R.fail(SectionHeader, -1, R.position(), err)
+	}
+
+	/* Only v4 keeps skinning, the rest is still read so a short file fails like a decode */
+	faces, lodsTo, skinningTo := header.NumFaces, dst, io.Discard
+	var err error
+	switch to {
+	case MeshVersion2:
+		skip := uint64(header.NumVerts)*uint64(VertexModernSize) + uint64(header.NumFaces)*uint64(FaceSize)
+		if header.NumBones > 0 {
+			skip += uint64(header.NumVerts) * uint64(EnvelopeSize)
+		}
+		if faces, err = R.peekLod0(skip, header.NumLods, header.NumFaces); err != nil {
+			return err
+		}
+		lodsTo = io.Discard
+		err = writeHeader(dst, line, MeshHeader2{
+			Header2Size, VertexModernSize, FaceSize, header.NumVerts, faces,
+		})
+	case MeshVersion3:
+		err = writeHeader(dst, line, MeshHeader3{
+			Header3Size, VertexModernSize, FaceSize, 4,
+			header.NumLods, header.NumVerts, header.NumFaces,
+		})
+	case MeshVersion4:
+		skinningTo = dst
+		err = writeHeader(dst, line, header)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := R.pipeSection(dst, SectionVertices, uint64(header.NumVerts), int(VertexModernSize), nil); err != nil {
+		return err
+	}
+	if header.NumBones > 0 {
+		if err := R.pipeSection(skinningTo, SectionEnvelopes, uint64(header.NumVerts), int(EnvelopeSize), nil); err != nil {
+			return err
+		}
+	}
+	if err := R.transcodeFaces(dst, faces, header.NumFaces); err != nil {
+		return err
+	}
+	if err := R.pipeSection(lodsTo, SectionLods, uint64(header.NumLods), 4, nil); err != nil {
+		return err
+	}
+	if err := R.pipeSection(skinningTo, SectionBones, uint64(header.NumBones), int(BoneSize), nil); err != nil {
+		return err
+	}
+	if err := R.pipeSection(skinningTo, SectionNames, uint64(header.SizeOf_bone_names_Buffer), 1, nil); err != nil {
+		return err
+	}
+	return R.pipeSection(skinningTo, SectionSubsets, uint64(header.NumSubsets), int(MeshSubsetSize), nil)
+}
+
+/* Writes the first keep faces and reads past the rest */
+func (R *meshReader) transcodeFaces(dst io.Writer, keep uint32, total uint32) error {
+	if err := R.pipeSection(dst, SectionFaces, uint64(keep), int(FaceSize), nil); err != nil {
+		return err
+	}
+	if keep == total {
+		return nil
+	}
+	skipped := R.pipeSection(io.Discard, SectionFaces, uint64(total-keep), int(FaceSize), nil)
+	var decodeErr *DecodeError
+	if errors.As(skipped, &decodeErr) {
+		decodeErr.Index += int(keep)
+	}
+	return skipped
+}
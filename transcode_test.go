@@ -0,0 +1,104 @@
+package mesh_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/MojaveMF/mesh"
+	"io"
+	"os"
+	"testing"
+)
+
+/* Hides Seek and Len so the transcoder only sees a plain stream */
+type plainReader struct {
+	io.Reader
+}
+
+func noRgbaMesh() []byte {
+	rgba := twoTriangles().ExportV2().(*mesh.Mesh2Rgba)
+	noRgba := mesh.Mesh2NoRgba{Header: rgba.Header, Faces: rgba.Faces}
+	noRgba.Header.VertexSize = 36
+	for _, vertex := range rgba.Verts {
+		noRgba.Verts = append(noRgba.Verts, vertex.NoColor())
+	}
+	buffer := bytes.Buffer{}
+	noRgba.Write(&buffer)
+	return buffer.Bytes()
+}
+
+func TestTranscode(t *testing.T) {
+	inputs := map[string][]byte{"norgba": noRgbaMesh()}
+	for _, path := range []string{"./testdata/output.v2", "./testdata/output.v3", "./testdata/output.v4"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[path] = data
+	}
+
+	for name, data := range inputs {
+		decoded, err := mesh.DecodeMesh(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, target := range []uint8{mesh.MeshVersion2, mesh.MeshVersion3, mesh.MeshVersion4} {
+			expected := bytes.Buffer{}
+			if err := mesh.EncodeMeshVersion(decoded, target).Write(&expected); err != nil {
+				t.Fatal(err)
+			}
+			for _, src := range []io.Reader{bytes.NewReader(data), plainReader{bytes.NewReader(data)}} {
+				output := bytes.Buffer{}
+				if err := mesh.Transcode(src, &output, target); err != nil {
+					t.Fatalf("%s to %d: %s", name, target, err)
+				}
+				if !bytes.Equal(output.Bytes(), expected.Bytes()) {
+					t.Errorf("%s to %d: output differs from decode and export", name, target)
+				}
+			}
+		}
+	}
+}
+
+func TestTranscodeEmpty(t *testing.T) {
+	empty := twoTriangles().ExportV2().(*mesh.Mesh2Rgba)
+	empty.Verts, empty.Faces = nil, nil
+	empty.Header.NumVerts, empty.Header.NumFaces = 0, 0
+	data := bytes.Buffer{}
+	if err := empty.Write(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := mustDecode(t, data.Bytes())
+	for _, target := range []uint8{mesh.MeshVersion3, mesh.MeshVersion4} {
+		expected := bytes.Buffer{}
+		if err := mesh.EncodeMeshVersion(decoded, target).Write(&expected); err != nil {
+			t.Fatal(err)
+		}
+		output := bytes.Buffer{}
+		if err := mesh.Transcode(bytes.NewReader(data.Bytes()), &output, target); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(output.Bytes(), expected.Bytes()) {
+			t.Errorf("to %d: streaming and re-encoding an empty mesh differ", target)
+		}
+	}
+}
+
+func TestTranscodeTruncated(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	short := data[:len(data)-10]
+
+	_, expected := mesh.DecodeMesh(plainReader{bytes.NewReader(short)})
+	err = mesh.Transcode(plainReader{bytes.NewReader(short)}, io.Discard, mesh.MeshVersion3)
+	var decodeErr *mesh.DecodeError
+	if !errors.As(err, &decodeErr) || err.Error() != expected.Error() {
+		t.Errorf("got %v expected %v", err, expected)
+	}
+
+	if err := mesh.Transcode(bytes.NewReader(data), io.Discard, mesh.MeshVersion1); !errors.Is(err, mesh.ErrBadMeshVersion) {
+		t.Errorf("expected bad version got %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package mesh
+
+import "math"
+
+/* Row major, applied to column vectors (p' = M * p) */
+type Matrix4 [4][4]float32
+
+func IdentityMatrix() Matrix4 {
+	return Matrix4{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	}
+}
+
+func ScaleMatrix(x, y, z float32) Matrix4 {
+	return Matrix4{
+		{x, 0, 0, 0},
+		{0, y, 0, 0},
+		{0, 0, z, 0},
+		{0, 0, 0, 1},
+	}
+}
+
+func TranslationMatrix(x, y, z float32) Matrix4 {
+	return Matrix4{
+		{1, 0, 0, x},
+		{0, 1, 0, y},
+		{0, 0, 1, z},
+		{0, 0, 0, 1},
+	}
+}
+
+/* Rotates around axis by angle (radians), axis does not need to be normalized */
+func RotationMatrix(axis Vector3, angle float32) Matrix4 {
+	x, y, z := normalize3(float64(axis.X), float64(axis.Y), float64(axis.Z))
+	s, c := math.Sincos(float64(angle))
+	t := 1 - c
+
+	return Matrix4{
+		{float32(t*x*x + c), float32(t*x*y - s*z), float32(t*x*z + s*y), 0},
+		{float32(t*x*y + s*z), float32(t*y*y + c), float32(t*y*z - s*x), 0},
+		{float32(t*x*z - s*y), float32(t*y*z + s*x), float32(t*z*z + c), 0},
+		{0, 0, 0, 1},
+	}
+}
+
+/* Reflects across the plane through the origin with the given normal */
+func MirrorMatrix(normal Vector3) Matrix4 {
+	x, y, z := normalize3(float64(normal.X), float64(normal.Y), float64(normal.Z))
+
+	return Matrix4{
+		{float32(1 - 2*x*x), float32(-2 * x * y), float32(-2 * x * z), 0},
+		{float32(-2 * x * y), float32(1 - 2*y*y), float32(-2 * y * z), 0},
+		{float32(-2 * x * z), float32(-2 * y * z), float32(1 - 2*z*z), 0},
+		{0, 0, 0, 1},
+	}
+}
+
+/* Returns M * B, so B is applied first */
+func (M Matrix4) Mul(B Matrix4) Matrix4 {
+	var out Matrix4
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			var sum float32
+			for i := 0; i < 4; i++ {
+				sum += M[row][i] * B[i][col]
+			}
+			out[row][col] = sum
+		}
+	}
+	return out
+}
+
+/* Determinant of the upper 3x3, negative means the transform mirrors */
+func (M Matrix4) Determinant3() float32 {
+	return M[0][0]*(M[1][1]*M[2][2]-M[1][2]*M[2][1]) -
+		M[0][1]*(M[1][0]*M[2][2]-M[1][2]*M[2][0]) +
+		M[0][2]*(M[1][0]*M[2][1]-M[1][1]*M[2][0])
+}
+
+func (M Matrix4) TransformPoint(x, y, z float32) (float32, float32, float32) {
+	return M[0][0]*x + M[0][1]*y + M[0][2]*z + M[0][3],
+		M[1][0]*x + M[1][1]*y + M[1][2]*z + M[1][3],
+		M[2][0]*x + M[2][1]*y + M[2][2]*z + M[2][3]
+}
+
+func (M Matrix4) TransformDirection(x, y, z float32) (float32, float32, float32) {
+	return M[0][0]*x + M[0][1]*y + M[0][2]*z,
+		M[1][0]*x + M[1][1]*y + M[1][2]*z,
+		M[2][0]*x + M[2][1]*y + M[2][2]*z
+}
+
+func normalize3(x, y, z float64) (float64, float64, float64) {
+	length := math.Sqrt(x*x + y*y + z*z)
+	if length == 0 {
+		return x, y, z
+	}
+	return x / length, y / length, z / length
+}
+
+func normalize3f(x, y, z float32) (float32, float32, float32) {
+	nx, ny, nz := normalize3(float64(x), float64(y), float64(z))
+	return float32(nx), float32(ny), float32(nz)
+}
+
+/* Everything needed to move a vertex, worked out once per mesh */
+type transformer struct {
+	matrix   Matrix4
+	normal   Matrix4 /* inverse transpose of the upper 3x3 */
+	rotation Matrix4 /* upper 3x3 with the scale taken out */
+	scale    float32 /* largest axis scale, used for bone culling radius */
+	mirrored bool
+}
+
+func newTransformer(M Matrix4) *transformer {
+	t := transformer{matrix: M, mirrored: M.Determinant3() < 0}
+
+	/* Inverse transpose is the cofactor matrix over the determinant */
+	det := M.Determinant3()
+	t.normal = IdentityMatrix()
+	if det != 0 {
+		t.normal[0][0] = (M[1][1]*M[2][2] - M[1][2]*M[2][1]) / det
+		t.normal[0][1] = (M[1][2]*M[2][0] - M[1][0]*M[2][2]) / det
+		t.normal[0][2] = (M[1][0]*M[2][1] - M[1][1]*M[2][0]) / det
+		t.normal[1][0] = (M[0][2]*M[2][1] - M[0][1]*M[2][2]) / det
+		t.normal[1][1] = (M[0][0]*M[2][2] - M[0][2]*M[2][0]) / det
+		t.normal[1][2] = (M[0][1]*M[2][0] - M[0][0]*M[2][1]) / det
+		t.normal[2][0] = (M[0][1]*M[1][2] - M[0][2]*M[1][1]) / det
+		t.normal[2][1] = (M[0][2]*M[1][0] - M[0][0]*M[1][2]) / det
+		t.normal[2][2] = (M[0][0]*M[1][1] - M[0][1]*M[1][0]) / det
+	}
+
+	/* Gram-Schmidt on the columns, keeps any reflection the matrix has */
+	t.rotation = IdentityMatrix()
+	var columns [3][3]float64
+	for col := 0; col < 3; col++ {
+		x, y, z := float64(M[0][col]), float64(M[1][col]), float64(M[2][col])
+		if length := float32(math.Sqrt(x*x + y*y + z*z)); length > t.scale {
+			t.scale = length
+		}
+		for prev := 0; prev < col; prev++ {
+			dot := x*columns[prev][0] + y*columns[prev][1] + z*columns[prev][2]
+			x -= dot * columns[prev][0]
+			y -= dot * columns[prev][1]
+			z -= dot * columns[prev][2]
+		}
+		x, y, z = normalize3(x, y, z)
+		columns[col] = [3]float64{x, y, z}
+		t.rotation[0][col] = float32(x)
+		t.rotation[1][col] = float32(y)
+		t.rotation[2][col] = float32(z)
+	}
+
+	return &t
+}
+
+func (t *transformer) position(x, y, z *float32) {
+	*x, *y, *z = t.matrix.TransformPoint(*x, *y, *z)
+}
+
+func (t *transformer) normalVector(x, y, z *float32) {
+	*x, *y, *z = normalize3f(t.normal.TransformDirection(*x, *y, *z))
+}
+
+/* Tangents are packed into int8 with 127 being 1.0 */
+func (t *transformer) tangent(x, y, z, s *int8) {
+	fx, fy, fz := t.rotation.TransformDirection(float32(*x)/127, float32(*y)/127, float32(*z)/127)
+	*x, *y, *z = packTangent(fx), packTangent(fy), packTangent(fz)
+	if t.mirrored {
+		*s = -*s
+	}
+}
+
+func packTangent(value float32) int8 {
+	scaled := math.Round(float64(value) * 127)
+	if scaled > 127 {
+		return 127
+	} else if scaled < -127 {
+		return -127
+	}
+	return int8(scaled)
+}
+
+func (t *transformer) bone(bone *Bone) {
+	bone.X, bone.Y, bone.Z = t.matrix.TransformPoint(bone.X, bone.Y, bone.Z)
+	bone.Culling *= t.scale
+
+	rotation := [3][3]float32{
+		{bone.R00, bone.R01, bone.R02},
+		{bone.R10, bone.R11, bone.R12},
+		{bone.R20, bone.R21, bone.R22},
+	}
+	var out [3][3]float32
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			out[row][col] = t.rotation[row][0]*rotation[0][col] +
+				t.rotation[row][1]*rotation[1][col] +
+				t.rotation[row][2]*rotation[2][col]
+		}
+	}
+	/* A bone cant hold a reflection so flip its X axis to stay right handed */
+	if t.mirrored {
+		out[0][0], out[1][0], out[2][0] = -out[0][0], -out[1][0], -out[2][0]
+	}
+
+	bone.R00, bone.R01, bone.R02 = out[0][0], out[0][1], out[0][2]
+	bone.R10, bone.R11, bone.R12 = out[1][0], out[1][1], out[1][2]
+	bone.R20, bone.R21, bone.R22 = out[2][0], out[2][1], out[2][2]
+}
+
+func (t *transformer) faces(faces []Face) {
+	if !t.mirrored {
+		return
+	}
+	for i := range faces {
+		faces[i].B, faces[i].C = faces[i].C, faces[i].B
+	}
+}
+
+func (V *VertexModern) transform(t *transformer) {
+	t.position(&V.Px, &V.Py, &V.Pz)
+	t.normalVector(&V.Nx, &V.Ny, &V.Nz)
+	t.tangent(&V.Tx, &V.Ty, &V.Tz, &V.Ts)
+}
+
+func (V *VertexNoRgba) transform(t *transformer) {
+	t.position(&V.Px, &V.Py, &V.Pz)
+	t.normalVector(&V.Nx, &V.Ny, &V.Nz)
+	t.tangent(&V.Tx, &V.Ty, &V.Tz, &V.Ts)
+}
+
+func (V *VertexV1) transform(t *transformer) {
+	t.position(&V.Px, &V.Py, &V.Pz)
+	t.normalVector(&V.Nx, &V.Ny, &V.Nz)
+}
+
+/* Transforms are applied in place, slices shared with exported meshes change too */
+func (M *Mesh1) Transform(matrix Matrix4) {
+	t := newTransformer(matrix)
+	for i := range M.Verts {
+		M.Verts[i].transform(t)
+	}
+	/* Faces are implied by vertex order so swap the vertices instead */
+	if t.mirrored {
+		for i := 0; i+2 < len(M.Verts); i += 3 {
+			M.Verts[i+1], M.Verts[i+2] = M.Verts[i+2], M.Verts[i+1]
+		}
+	}
+}
+
+func (M *Mesh2NoRgba) Transform(matrix Matrix4) {
+	t := newTransformer(matrix)
+	for i := range M.Verts {
+		M.Verts[i].transform(t)
+	}
+	t.faces(M.Faces)
+}
+
+func (M *Mesh2Rgba) Transform(matrix Matrix4) {
+	t := newTransformer(matrix)
+	for i := range M.Verts {
+		M.Verts[i].transform(t)
+	}
+	t.faces(M.Faces)
+}
+
+func (M *Mesh3) Transform(matrix Matrix4) {
+	t := newTransformer(matrix)
+	for i := range M.Verts {
+		M.Verts[i].transform(t)
+	}
+	t.faces(M.Faces)
+}
+
+func (M *Mesh4) Transform(matrix Matrix4) {
+	t := newTransformer(matrix)
+	for i := range M.Verts {
+		M.Verts[i].transform(t)
+	}
+	t.faces(M.Faces)
+	for i := range M.Bones {
+		t.bone(&M.Bones[i])
+	}
+}
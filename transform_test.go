@@ -0,0 +1,70 @@
+package mesh_test
+
+import (
+	"github.com/MojaveMF/mesh"
+	"math"
+	"os"
+	"testing"
+)
+
+func closeTo(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-4
+}
+
+func TestTransformMatrix(t *testing.T) {
+	rotate := mesh.RotationMatrix(mesh.Vector3{Z: 1}, math.Pi/2)
+	x, y, z := rotate.TransformPoint(1, 0, 0)
+	if !closeTo(x, 0) || !closeTo(y, 1) || !closeTo(z, 0) {
+		t.Errorf("rotation gave %f,%f,%f", x, y, z)
+	}
+
+	combined := mesh.TranslationMatrix(1, 2, 3).Mul(mesh.ScaleMatrix(2, 2, 2))
+	x, y, z = combined.TransformPoint(1, 1, 1)
+	if x != 3 || y != 4 || z != 5 {
+		t.Errorf("scale then translate gave %f,%f,%f", x, y, z)
+	}
+
+	if mesh.MirrorMatrix(mesh.Vector3{X: 1}).Determinant3() >= 0 {
+		t.Error("mirror should have a negative determinant")
+	}
+}
+
+func TestTransformV4(t *testing.T) {
+	file, err := os.Open("./testdata/output.v4")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer file.Close()
+
+	decoded, err := mesh.DecodeMesh(file)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	meshData := decoded.(*mesh.Mesh4)
+	before := meshData.Verts[0]
+	face := meshData.Faces[0]
+	bone := meshData.Bones[3]
+
+	meshData.Transform(mesh.ScaleMatrix(2, 3, 4))
+	after := meshData.Verts[0]
+	if !closeTo(after.Px, before.Px*2) || !closeTo(after.Py, before.Py*3) || !closeTo(after.Pz, before.Pz*4) {
+		t.Errorf("position not scaled %+v", after)
+	}
+	length := after.Nx*after.Nx + after.Ny*after.Ny + after.Nz*after.Nz
+	if !closeTo(length, 1) {
+		t.Errorf("normal not normalized, length %f", length)
+	}
+	if !closeTo(meshData.Bones[3].Y, bone.Y*3) {
+		t.Errorf("bone not moved %+v", meshData.Bones[3])
+	}
+
+	meshData.Transform(mesh.MirrorMatrix(mesh.Vector3{X: 1}))
+	if meshData.Faces[0].B != face.C || meshData.Faces[0].C != face.B {
+		t.Errorf("winding not flipped %+v", meshData.Faces[0])
+	}
+	if !closeTo(meshData.Verts[0].Px, -after.Px) {
+		t.Errorf("position not mirrored %+v", meshData.Verts[0])
+	}
+}
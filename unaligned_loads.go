@@ -0,0 +1,6 @@
+//go:build amd64 || 386 || arm64 || ppc64le
+
+package mesh
+
+/* These read floats and ints at any address, so sections are aliased wherever they start */
+const unalignedAccess = true
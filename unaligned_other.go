@@ -0,0 +1,6 @@
+//go:build !(amd64 || 386 || arm64 || ppc64le)
+
+package mesh
+
+/* Sections have to sit aligned for their type before they are aliased */
+const unalignedAccess = false
@@ -0,0 +1,338 @@
+package mesh
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+/* Parts of a mesh file, in the order they are stored */
+type Section string
+
+const (
+	SectionHeader    Section = "header"
+	SectionVertices  Section = "vertices"
+	SectionEnvelopes Section = "envelopes"
+	SectionFaces     Section = "faces"
+	SectionLods      Section = "lods"
+	SectionBones     Section = "bones"
+	SectionNames     Section = "names"
+	SectionSubsets   Section = "subsets"
+)
+
+type Problem struct {
+	Section Section
+	Index   int /* element in the section, -1 when it is about the whole section */
+	Message string
+}
+
+func (P Problem) String() string {
+	if P.Index < 0 {
+		return fmt.Sprintf("%s: %s", P.Section, P.Message)
+	}
+	return fmt.Sprintf("%s[%d]: %s", P.Section, P.Index, P.Message)
+}
+
+/* Every problem found, not just the first one */
+type Problems []Problem
+
+func (P Problems) Error() string {
+	lines := make([]string, len(P))
+	for i, problem := range P {
+		lines[i] = problem.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+/* nil when there is nothing wrong so it can be returned as an error */
+func (P Problems) Err() error {
+	if len(P) == 0 {
+		return nil
+	}
+	return P
+}
+
+func (P Problems) Section(section Section) Problems {
+	out := Problems{}
+	for _, problem := range P {
+		if problem.Section == section {
+			out = append(out, problem)
+		}
+	}
+	return out
+}
+
+func (P *Problems) add(section Section, index int, format string, args ...any) {
+	*P = append(*P, Problem{section, index, fmt.Sprintf(format, args...)})
+}
+
+func badFloat(values ...float32) bool {
+	for _, value := range values {
+		if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+			return true
+		}
+	}
+	return false
+}
+
+func (P *Problems) count(section Section, name string, header uint64, actual int) {
+	if header != uint64(actual) {
+		P.add(section, -1, "header says %d %s but there are %d", header, name, actual)
+	}
+}
+
+func (P *Problems) modernVerts(verts []VertexModern) {
+	for i, vertex := range verts {
+		if badFloat(vertex.Px, vertex.Py, vertex.Pz, vertex.Nx, vertex.Ny, vertex.Nz, vertex.Tu, vertex.Tv) {
+			P.add(SectionVertices, i, "NaN or Inf in vertex")
+		}
+	}
+}
+
+func (P *Problems) faces(faces []Face, numVerts int) {
+	for i, face := range faces {
+		if !faceInRange(face, numVerts) {
+			P.add(SectionFaces, i, "face %d,%d,%d points past %d verts", face.A, face.B, face.C, numVerts)
+		}
+	}
+}
+
+func (P *Problems) lods(lods []uint32, numFaces int) {
+	for i, lod := range lods {
+		if lod > uint32(numFaces) {
+			P.add(SectionLods, i, "offset %d is past %d faces", lod, numFaces)
+		}
+		if i > 0 && lod < lods[i-1] {
+			P.add(SectionLods, i, "offset %d is before the previous offset %d", lod, lods[i-1])
+		}
+	}
+}
+
+func (M *Mesh1) Validate() Problems {
+	problems := Problems{}
+	if len(M.Verts)%3 != 0 {
+		problems.add(SectionVertices, -1, "%d verts is not a whole number of faces", len(M.Verts))
+	}
+	problems.count(SectionHeader, "faces", uint64(M.FaceCount), len(M.Verts)/3)
+	for i, vertex := range M.Verts {
+		if badFloat(vertex.Px, vertex.Py, vertex.Pz, vertex.Nx, vertex.Ny, vertex.Nz, vertex.Tu, vertex.Tv) {
+			problems.add(SectionVertices, i, "NaN or Inf in vertex")
+		}
+	}
+	return problems
+}
+
+func (M *Mesh2NoRgba) Validate() Problems {
+	problems := Problems{}
+	if M.Header.VertexSize != VertexNoRgbaSize {
+		problems.add(SectionHeader, -1, "vertex size is %d but NoRgba verts are %d", M.Header.VertexSize, VertexNoRgbaSize)
+	}
+	if M.Header.FaceSize != FaceSize {
+		problems.add(SectionHeader, -1, "face size is %d expected %d", M.Header.FaceSize, FaceSize)
+	}
+	problems.count(SectionHeader, "verts", uint64(M.Header.NumVerts), len(M.Verts))
+	problems.count(SectionHeader, "faces", uint64(M.Header.NumFaces), len(M.Faces))
+
+	for i, vertex := range M.Verts {
+		if badFloat(vertex.Px, vertex.Py, vertex.Pz, vertex.Nx, vertex.Ny, vertex.Nz, vertex.Tu, vertex.Tv) {
+			problems.add(SectionVertices, i, "NaN or Inf in vertex")
+		}
+	}
+	problems.faces(M.Faces, len(M.Verts))
+	return problems
+}
+
+func (M *Mesh2Rgba) Validate() Problems {
+	problems := Problems{}
+	if M.Header.VertexSize != VertexModernSize {
+		problems.add(SectionHeader, -1, "vertex size is %d but Rgba verts are %d", M.Header.VertexSize, VertexModernSize)
+	}
+	if M.Header.FaceSize != FaceSize {
+		problems.add(SectionHeader, -1, "face size is %d expected %d", M.Header.FaceSize, FaceSize)
+	}
+	problems.count(SectionHeader, "verts", uint64(M.Header.NumVerts), len(M.Verts))
+	problems.count(SectionHeader, "faces", uint64(M.Header.NumFaces), len(M.Faces))
+
+	problems.modernVerts(M.Verts)
+	problems.faces(M.Faces, len(M.Verts))
+	return problems
+}
+
+func (M *Mesh3) Validate() Problems {
+	problems := Problems{}
+	if M.Header.VertexSize != VertexModernSize {
+		problems.add(SectionHeader, -1, "vertex size is %d expected %d", M.Header.VertexSize, VertexModernSize)
+	}
+	if M.Header.FaceSize != FaceSize {
+		problems.add(SectionHeader, -1, "face size is %d expected %d", M.Header.FaceSize, FaceSize)
+	}
+	if M.Header.SizeofLod != 4 {
+		problems.add(SectionHeader, -1, "lod size is %d expected 4", M.Header.SizeofLod)
+	}
+	problems.count(SectionHeader, "verts", uint64(M.Header.NumVerts), len(M.Verts))
+	problems.count(SectionHeader, "faces", uint64(M.Header.NumFaces), len(M.Faces))
+	problems.count(SectionHeader, "lods", uint64(M.Header.NumLods), len(M.Lods))
+
+	problems.modernVerts(M.Verts)
+	problems.faces(M.Faces, len(M.Verts))
+	problems.lods(M.Lods, len(M.Faces))
+	return problems
+}
+
+func (M *Mesh4) Validate() Problems {
+	problems := Problems{}
+	problems.count(SectionHeader, "verts", uint64(M.Header.NumVerts), len(M.Verts))
+	problems.count(SectionHeader, "faces", uint64(M.Header.NumFaces), len(M.Faces))
+	problems.count(SectionHeader, "lods", uint64(M.Header.NumLods), len(M.Lods))
+	problems.count(SectionHeader, "bones", uint64(M.Header.NumBones), len(M.Bones))
+	problems.count(SectionHeader, "name table bytes", uint64(M.Header.SizeOf_bone_names_Buffer), len(M.NameTable))
+	problems.count(SectionHeader, "subsets", uint64(M.Header.NumSubsets), len(M.MeshSubsets))
+	if len(M.Bones) > 0 && len(M.Envelopes) != len(M.Verts) {
+		problems.add(SectionEnvelopes, -1, "%d envelopes for %d verts", len(M.Envelopes), len(M.Verts))
+	}
+
+	problems.modernVerts(M.Verts)
+	problems.faces(M.Faces, len(M.Verts))
+	problems.lods(M.Lods, len(M.Faces))
+	problems.bones(M)
+	problems.subsets(M)
+	problems.envelopes(M)
+	return problems
+}
+
+func (P *Problems) bones(M *Mesh4) {
+	numBones := len(M.Bones)
+	for i, bone := range M.Bones {
+		if bone.ParentIndex != NoBone && int(bone.ParentIndex) >= numBones {
+			P.add(SectionBones, i, "parent %d is past %d bones", bone.ParentIndex, numBones)
+		}
+		if bone.LodParentIndex != NoBone && int(bone.LodParentIndex) >= numBones {
+			P.add(SectionBones, i, "lod parent %d is past %d bones", bone.LodParentIndex, numBones)
+		}
+		if badFloat(bone.Culling, bone.R00, bone.R01, bone.R02, bone.R10, bone.R11, bone.R12, bone.R20, bone.R21, bone.R22, bone.X, bone.Y, bone.Z) {
+			P.add(SectionBones, i, "NaN or Inf in bone")
+		}
+
+		if bone.BoneNameIndex >= uint32(len(M.NameTable)) {
+			P.add(SectionNames, i, "name offset %d is past the %d byte name table", bone.BoneNameIndex, len(M.NameTable))
+		} else if !containsByte(M.NameTable[bone.BoneNameIndex:], 0) {
+			P.add(SectionNames, i, "name at offset %d is not null terminated", bone.BoneNameIndex)
+		}
+	}
+
+	for _, cycle := range boneCycles(M.Bones) {
+		P.add(SectionBones, cycle[0], "parent chain of %d bones loops back to itself", len(cycle))
+	}
+}
+
+/* Each cycle in the parent chains, starting at its lowest bone */
+func boneCycles(bones []Bone) [][]int {
+	const (
+		unseen = iota
+		walking
+		done
+	)
+	state := make([]byte, len(bones))
+	cycles := make([][]int, 0)
+
+	for start := range bones {
+		path := make([]int, 0)
+		current := start
+		for current >= 0 && current < len(bones) && state[current] == unseen {
+			state[current] = walking
+			path = append(path, current)
+			if parent := bones[current].ParentIndex; parent != NoBone {
+				current = int(parent)
+			} else {
+				current = -1
+			}
+		}
+
+		if current >= 0 && current < len(bones) && state[current] == walking {
+			cycle := make([]int, 0)
+			lowest := 0
+			for i := len(path) - 1; i >= 0; i-- {
+				cycle = append(cycle, path[i])
+				if path[i] < cycle[lowest] {
+					lowest = len(cycle) - 1
+				}
+				if path[i] == current {
+					break
+				}
+			}
+			cycles = append(cycles, append(cycle[lowest:], cycle[:lowest]...))
+		}
+		for _, bone := range path {
+			state[bone] = done
+		}
+	}
+	return cycles
+}
+
+func containsByte(data []byte, value byte) bool {
+	for _, char := range data {
+		if char == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (P *Problems) subsets(M *Mesh4) {
+	for i, subset := range M.MeshSubsets {
+		if uint64(subset.FacesBegin)+uint64(subset.FacesLength) > uint64(len(M.Faces)) {
+			P.add(SectionSubsets, i, "faces %d+%d go past %d faces", subset.FacesBegin, subset.FacesLength, len(M.Faces))
+		}
+		if uint64(subset.VertsBegin)+uint64(subset.VertsLength) > uint64(len(M.Verts)) {
+			P.add(SectionSubsets, i, "verts %d+%d go past %d verts", subset.VertsBegin, subset.VertsLength, len(M.Verts))
+		}
+		if subset.NumBonesIndicies > MaxSubsetBones {
+			P.add(SectionSubsets, i, "%d bones is more than the %d allowed", subset.NumBonesIndicies, MaxSubsetBones)
+		}
+		for b := uint32(0); b < subset.NumBonesIndicies && b < MaxSubsetBones; b++ {
+			if int(subset.BoneIndicies[b]) >= len(M.Bones) {
+				P.add(SectionSubsets, i, "bone %d is %d which is past %d bones", b, subset.BoneIndicies[b], len(M.Bones))
+			}
+		}
+	}
+}
+
+/* The subset owning each envelope, -1 when none does */
+func envelopeOwners(M *Mesh4) []int {
+	owner := make([]int, len(M.Envelopes))
+	for i := range owner {
+		owner[i] = -1
+	}
+	for s, subset := range M.MeshSubsets {
+		for v := subset.VertsBegin; v < subset.VertsBegin+subset.VertsLength && v < uint32(len(owner)); v++ {
+			owner[v] = s
+		}
+	}
+	return owner
+}
+
+/* Envelope bones are indicies into the bone list of the subset owning the vertex */
+func (P *Problems) envelopes(M *Mesh4) {
+	if len(M.Bones) == 0 {
+		return
+	}
+	owner := envelopeOwners(M)
+	for i, envelope := range M.Envelopes {
+		for slot := 0; slot < 4; slot++ {
+			if envelope.Weights[slot] == 0 {
+				continue
+			}
+			bone := uint32(envelope.Bones[slot])
+			if len(M.MeshSubsets) == 0 {
+				if bone >= uint32(len(M.Bones)) {
+					P.add(SectionEnvelopes, i, "bone %d is past %d bones", bone, len(M.Bones))
+				}
+			} else if owner[i] < 0 {
+				P.add(SectionEnvelopes, i, "weighted vertex is not in any subset")
+				break
+			} else if limit := min(M.MeshSubsets[owner[i]].NumBonesIndicies, MaxSubsetBones); bone >= limit {
+				P.add(SectionEnvelopes, i, "bone %d is past the %d bones of subset %d", bone, limit, owner[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package mesh_test
+
+import (
+	"github.com/MojaveMF/mesh"
+	"testing"
+)
+
+func TestValidateTestdata(t *testing.T) {
+	for _, path := range []string{"./testdata/output.v2", "./testdata/output.v3", "./testdata/output.v4"} {
+		meshData := decodeFile(t, path)
+		validator, ok := meshData.(interface{ Validate() mesh.Problems })
+		if !ok {
+			t.Errorf("%s has no Validate", path)
+			continue
+		}
+		if err := validator.Validate().Err(); err != nil {
+			t.Errorf("%s: %s", path, err)
+		}
+	}
+}
+
+func TestValidateBroken(t *testing.T) {
+	mesh4 := decodeFile(t, "./testdata/output.v4").(*mesh.Mesh4)
+	mesh4.Faces[10].B = 99999
+	mesh4.Lods[2] = 1
+	mesh4.Bones[0].ParentIndex = 4
+	mesh4.Bones[1].BoneNameIndex = 5000
+	mesh4.Envelopes[0] = mesh.Envelope{Bones: [4]byte{7}, Weights: [4]byte{255}}
+	mesh4.MeshSubsets[0].VertsLength = 5000
+	mesh4.Header.NumFaces++
+
+	problems := mesh4.Validate()
+	for _, section := range []mesh.Section{
+		mesh.SectionHeader,
+		mesh.SectionFaces,
+		mesh.SectionLods,
+		mesh.SectionBones,
+		mesh.SectionNames,
+		mesh.SectionEnvelopes,
+		mesh.SectionSubsets,
+	} {
+		if len(problems.Section(section)) == 0 {
+			t.Errorf("nothing reported for %s", section)
+		}
+	}
+
+	cycles := problems.Section(mesh.SectionBones)
+	if len(cycles) != 1 || cycles[0].Index != 0 {
+		t.Errorf("expected one cycle at bone 0 got %v", cycles)
+	}
+}
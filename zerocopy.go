@@ -0,0 +1,134 @@
+package mesh
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"unsafe"
+)
+
+/*
+Decodes straight out of data. Sections are not copied, the mesh slices point
+into data so changing one changes the other. Architectures that cant load from
+any address only alias sections that sit aligned for their type, the rest get
+copied out like a normal decode.
+*/
+func DecodeBytes(data []byte) (Mesh, error) {
+	return DecodeBytesOptions(data, DecodeOptions{Limits: DefaultLimits})
+}
+
+func DecodeBytesOptions(data []byte, opts DecodeOptions) (Mesh, error) {
+	reader := newMeshReader(bytes.NewReader(data), opts, 0)
+	reader.data = data
+	decoded, _, err := decodeReader(reader, opts)
+	return decoded, err
+}
+
+/*
+Every section before the name table starts one byte past a 4 byte boundary
+(13 byte version line plus a 12, 16 or 24 byte header). Reading a file into a
+buffer from here lines them up so DecodeBytes can alias verts and faces on
+architectures that need it.
+*/
+func AlignedBuffer(size int) []byte {
+	if size <= 0 {
+		return []byte{}
+	}
+	backing := make([]uint32, (size+3+3)/4)
+	buffer := unsafe.Slice((*byte)(unsafe.Pointer(&backing[0])), len(backing)*4)
+	return buffer[3 : 3+size : 3+size]
+}
+
+/*
+Hands back the next declared items as a view of the input. Only done when the
+whole section is there, inside MaxBytes and aligned, anything else goes
+through the copying path so errors come out the same.
+*/
+func aliasSection[T any](R *meshReader, declared uint64) ([]T, bool) {
+	if R.data == nil || !littleEndianHost || declared == 0 {
+		return nil, false
+	}
+	var zero T
+	start := uint64(R.offset)
+	end := start + declared*uint64(unsafe.Sizeof(zero))
+	if end > uint64(len(R.data)) {
+		return nil, false
+	}
+	if limit := R.opts.Limits.MaxBytes; limit > 0 && end > uint64(limit) {
+		return nil, false
+	}
+	first := unsafe.Pointer(&R.data[start])
+	if !unalignedAccess && uintptr(first)%unsafe.Alignof(zero) != 0 {
+		return nil, false
+	}
+	seeker, ok := R.stream.(io.Seeker)
+	if !ok {
+		return nil, false
+	}
+	if _, err := seeker.Seek(int64(end-start), io.SeekCurrent); err != nil {
+		return nil, false
+	}
+	R.offset = int64(end)
+	return unsafe.Slice((*T)(first), declared), true
+}
+
+/*
+A decoded file. Mesh slices point into the mapping, so once Close unmaps it
+touching Verts, Faces or any other section faults or reads whatever took the
+memory over. Copy anything that has to outlive the file first.
+*/
+type MeshFile struct {
+	Mesh  Mesh
+	data  []byte
+	close func([]byte) error
+}
+
+func OpenFile(path string) (*MeshFile, error) {
+	return OpenFileOptions(path, DecodeOptions{Limits: DefaultLimits})
+}
+
+/* Partial meshes from lenient decodes come back with their error */
+func OpenFileOptions(path string, opts DecodeOptions) (*MeshFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, release, err := mapFile(file)
+	if err != nil {
+		data, release, err = readAligned(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mesh, err := DecodeBytesOptions(data, opts)
+	if mesh == nil {
+		release(data)
+		return nil, err
+	}
+	return &MeshFile{mesh, data, release}, err
+}
+
+/* Drops Mesh as well so it cant be reached through the file afterwards */
+func (F *MeshFile) Close() error {
+	if F.close == nil {
+		return nil
+	}
+	err := F.close(F.data)
+	F.Mesh, F.data, F.close = nil, nil, nil
+	return err
+}
+
+func readAligned(file *os.File) ([]byte, func([]byte) error, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	data := AlignedBuffer(int(info.Size()))
+	if _, err := io.ReadFull(file, data); err != nil {
+		return nil, nil, err
+	}
+	return data, func([]byte) error { return nil }, nil
+}
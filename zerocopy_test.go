@@ -0,0 +1,135 @@
+package mesh_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/MojaveMF/mesh"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestDecodeBytes(t *testing.T) {
+	for _, path := range []string{"./testdata/output.v2", "./testdata/output.v3", "./testdata/output.v4"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := decodeFile(t, path)
+
+		aligned := mesh.AlignedBuffer(len(data))
+		copy(aligned, data)
+		for _, input := range [][]byte{data, aligned} {
+			decoded, err := mesh.DecodeBytes(input)
+			if err != nil {
+				t.Fatalf("%s: %s", path, err)
+			}
+			if !reflect.DeepEqual(decoded, expected) {
+				t.Errorf("%s: decoded bytes differ from the stream decode", path)
+			}
+		}
+	}
+}
+
+func TestDecodeBytesAliases(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aligned := mesh.AlignedBuffer(len(data))
+	copy(aligned, data)
+
+	decoded, err := mesh.DecodeBytes(aligned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mesh4 := decoded.(*mesh.Mesh4)
+	mesh4.Faces[0] = mesh.Face{1, 2, 3}
+
+	again, err := mesh.DecodeBytes(aligned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.(*mesh.Mesh4).Faces[0] != (mesh.Face{1, 2, 3}) {
+		t.Error("faces were copied out of an aligned buffer")
+	}
+}
+
+func TestDecodeBytesLenient(t *testing.T) {
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := mesh.DecodeOptions{Lenient: true}
+	expected, expectedErr := mesh.DecodeMeshOptions(bytes.NewReader(data[:len(data)/2]), opts)
+	decoded, err := mesh.DecodeBytesOptions(data[:len(data)/2], opts)
+	if !errors.Is(err, mesh.ErrPartialMesh) || err.Error() != expectedErr.Error() {
+		t.Errorf("got %v expected %v", err, expectedErr)
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Error("partial decode differs from the stream decode")
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mesh.v4")
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := mesh.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(file.Mesh, decodeFile(t, "./testdata/output.v4")) {
+		t.Error("opened file differs from the stream decode")
+	}
+
+	/* Copy on write, nothing should reach the file */
+	file.Mesh.(*mesh.Mesh4).NameTable[0] = 'X'
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(after, data) {
+		t.Error("editing the mesh changed the file")
+	}
+}
+
+func TestDecodeBytesUnaligned(t *testing.T) {
+	switch runtime.GOARCH {
+	case "amd64", "386", "arm64", "ppc64le":
+	default:
+		t.Skip("unaligned sections are copied on", runtime.GOARCH)
+	}
+	data, err := os.ReadFile("./testdata/output.v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	/* Same start a mapped file gets, the version line and header leave verts and faces off by one */
+	decoded, err := mesh.DecodeBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mesh4 := decoded.(*mesh.Mesh4)
+	mesh4.Verts[0].Px = 42
+	mesh4.Faces[0] = mesh.Face{1, 2, 3}
+
+	again, err := mesh.DecodeBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.(*mesh.Mesh4).Verts[0].Px != 42 || again.(*mesh.Mesh4).Faces[0] != (mesh.Face{1, 2, 3}) {
+		t.Error("unaligned sections were copied out")
+	}
+}